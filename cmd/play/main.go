@@ -2,16 +2,96 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
-	"math/rand"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/midrange/rogue/game"
 )
 
+var deckPath = flag.String("deck", "delver", "your deck: "+deckChoices())
+var opponentDeckPath = flag.String("opponent-deck", "stompy", "your opponent's deck: "+deckChoices())
+
+// Setting either strategy plays games without the menu, and the other
+// strategy is human unless it is set too.
+var strategyName = flag.String("strategy", "", "your strategy, one of "+strings.Join(game.StrategyNames(), ", "))
+var opponentStrategyName = flag.String("opponent-strategy", "", "your opponent's strategy, one of "+strings.Join(game.StrategyNames(), ", "))
+var games = flag.Int("games", 1, "how many games to play, switching who is on the play each game")
+var startSeed = flag.Int64("seed", 0, "the seed of the first game and the bots, from the clock if it isn't set")
+var replayPath = flag.String("record", "", "a file to save a replay of the game to, for the replay command; with -games, each game's number is added to the name")
+
+// Parameters for the bots.
+var exploration = flag.Float64("c", game.DefaultBotConfig(0).C, "the exploration constant of mcst")
+var calculationTime = flag.Float64("time", game.DefaultBotConfig(0).CalculationTime, "how many seconds mcst thinks for each action")
+var playouts = flag.Int("playouts", 0, "if positive, how many playouts mcst does for each action instead of thinking for -time")
+var workers = flag.Int("workers", game.DefaultBotConfig(0).Workers, "how many goroutines mcst searches with")
+var command = flag.String("command", "", "the program that the external strategy runs, with its arguments")
+
+// The decks loaded from the flags.
+var deck, opponentDeck *game.Deck
+
 func main() {
+	flag.Parse()
+	deck = loadDeck(*deckPath)
+	opponentDeck = loadDeck(*opponentDeckPath)
+	if *strategyName != "" || *opponentStrategyName != "" {
+		playGames()
+	} else {
+		chooseGame()
+	}
+}
+
+func deckChoices() string {
+	return strings.Join(game.DeckNames(), ", ") + " or a text decklist file"
+}
+
+func loadDeck(nameOrPath string) *game.Deck {
+	d, err := game.LoadDeck(nameOrPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return d
+}
+
+// newStrategy returns a new strategy of the given name, configured from the flags.
+// Quiet bots don't print their thinking.
+func newStrategy(name string, seed int64, quiet bool) game.Strategy {
+	strategy, err := game.NewStrategy(name, game.BotConfig{
+		Seed:            seed,
+		C:               *exploration,
+		CalculationTime: *calculationTime,
+		Playouts:        *playouts,
+		Workers:         *workers,
+		Quiet:           quiet,
+		Command:         *command,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if b, ok := strategy.(*game.ExternalBot); ok && b.Err != nil {
+		fmt.Println(b.Err)
+		os.Exit(1)
+	}
+	return strategy
+}
+
+// yourDeck returns a fresh copy of your deck.
+func yourDeck() *game.Deck {
+	return deck.Copy()
+}
+
+// theirDeck returns a fresh copy of your opponent's deck.
+func theirDeck() *game.Deck {
+	return opponentDeck.Copy()
+}
+
+func chooseGame() {
 	text := showWelcomePrompt()
 	if text == "1" {
 		playHumanVsMcstBot()
@@ -21,8 +101,10 @@ func main() {
 		playHumanVsRandom()
 	} else if text == "4" {
 		playComputerVsComputer()
+	} else if text == "5" {
+		playMatchVsAttackBot()
 	} else {
-		main()
+		chooseGame()
 	}
 }
 
@@ -33,41 +115,161 @@ func showWelcomePrompt() string {
 	fmt.Println("2) Human vs AttackBot")
 	fmt.Println("3) Human vs Random")
 	fmt.Println("4) AI vs AI")
+	fmt.Println("5) Human vs AttackBot, best of three")
 	fmt.Print("\nEnter a number: ")
 	text, _ := reader.ReadString('\n')
 	return strings.TrimSpace(text)
 }
 
+// newSeed returns the -seed flag if it was set, or a seed from the clock, and
+// prints it so the game can be replayed.
+func newSeed() int64 {
+	seed := time.Now().UnixNano()
+	if isFlagSet("seed") {
+		seed = *startSeed
+	}
+	fmt.Printf("Seed: %d\n", seed)
+	return seed
+}
+
+// playGames plays the strategies from the flags against each other.
+func playGames() {
+	you := orDefault(*strategyName, "human")
+	them := orDefault(*opponentStrategyName, "human")
+	if *strategyName == "" {
+		fmt.Println("-strategy isn't set, so you play as a human")
+	}
+	if *opponentStrategyName == "" {
+		fmt.Println("-opponent-strategy isn't set, so your opponent plays as a human")
+	}
+	printGames := you == "human" || them == "human"
+	rng := game.NewRng(newSeed())
+	yourWins, theirWins := 0, 0
+	for i := 0; i < *games; i++ {
+		seed := rng.Int63()
+		yours := newStrategy(you, rng.Int63(), !printGames)
+		theirs := newStrategy(them, rng.Int63(), !printGames)
+		var winner string
+		if i%2 == 0 {
+			g := newGame(yourDeck(), theirDeck(), seed)
+			winner = seatName(game.PlayGame(g, yours, theirs, printGames), "you", "your opponent")
+			saveReplay(g, i, yours, theirs)
+		} else {
+			g := newGame(theirDeck(), yourDeck(), seed)
+			winner = seatName(game.PlayGame(g, theirs, yours, printGames), "your opponent", "you")
+			saveReplay(g, i, theirs, yours)
+		}
+		switch winner {
+		case "you":
+			yourWins++
+		case "your opponent":
+			theirWins++
+		}
+		for _, s := range []game.Strategy{yours, theirs} {
+			if c, ok := s.(io.Closer); ok {
+				c.Close()
+			}
+		}
+		fmt.Printf("Game %d (seed %d): %s won\n", i+1, seed, winner)
+	}
+	fmt.Printf("%s with %s won %d, %s with %s won %d, %d draws\n",
+		you, *deckPath, yourWins, them, *opponentDeckPath, theirWins, *games-yourWins-theirWins)
+}
+
+// newGame makes a game that records a replay if -record is set.
+func newGame(deckToPlay *game.Deck, deckToDraw *game.Deck, seed int64) *game.Game {
+	if *replayPath == "" {
+		return game.NewGame(deckToPlay, deckToDraw, seed)
+	}
+	return game.LoggedGame(deckToPlay, deckToDraw, seed)
+}
+
+// saveReplay saves the replay of the i-th game, if -record is set.
+func saveReplay(g *game.Game, i int, onThePlay game.Strategy, onTheDraw game.Strategy) {
+	if *replayPath == "" {
+		return
+	}
+	path := *replayPath
+	if *games > 1 {
+		ext := filepath.Ext(path)
+		path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), i+1, ext)
+	}
+	replay := g.Log.Replay
+	replay.Strategies = [2]string{onThePlay.String(), onTheDraw.String()}
+	if err := replay.Save(path); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// isFlagSet returns whether the flag with the given name was set on the
+// command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func orDefault(s string, defaultValue string) string {
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
+// seatName names the winner of a game, or "nobody" for a draw.
+func seatName(winner game.PlayerId, onThePlay string, onTheDraw string) string {
+	switch winner {
+	case game.OnThePlay:
+		return onThePlay
+	case game.OnTheDraw:
+		return onTheDraw
+	}
+	return "nobody"
+}
+
 func playHumanVsMcstBot() {
-	g := game.NewGame(game.MonoBlueDelver(), game.Stompy())
-	game.PlayGame(g, &game.Human{}, game.NewMcstBot(), true)
+	seed := newSeed()
+	g := game.NewGame(yourDeck(), theirDeck(), seed)
+	game.PlayGame(g, &game.Human{}, newStrategy("mcst", seed, false), true)
 }
 
 func playHumanVsAttackBot() {
-	g := game.NewGame(game.MonoBlueDelver(), game.Stompy())
+	g := game.NewGame(yourDeck(), theirDeck(), newSeed())
 	game.PlayGame(g, &game.Human{}, &game.AttackBot{}, true)
 }
 
 func playHumanVsRandom() {
-	g := game.NewGame(game.MonoBlueDelver(), game.Stompy())
+	g := game.NewGame(yourDeck(), theirDeck(), newSeed())
 	game.PlayGame(g, &game.Human{}, &game.RandomBot{}, true)
 }
 
+func playMatchVsAttackBot() {
+	m := game.NewMatch(yourDeck(), theirDeck(), &game.Human{}, &game.AttackBot{}, newSeed())
+	m.PrintResult = true
+	m.Play()
+}
+
 func playComputerVsComputer() {
+	rng := game.NewRng(newSeed())
 	i := 0
 	for start := time.Now(); time.Since(start) < time.Second; {
-		playOutGameRandomly()
+		playOutGameRandomly(rng)
 		i++
 	}
 	fmt.Printf("Played out %v games in 1 second\n", i)
 }
 
-func playOutGameRandomly() {
-	game := game.NewGame(game.Stompy(), game.MonoBlueDelver())
+func playOutGameRandomly(rng *game.Rng) {
+	game := game.NewGame(theirDeck(), yourDeck(), rng.Int63())
 
 	for {
 		actions := game.Actions(false)
-		randomAction := actions[rand.Int()%len(actions)]
+		randomAction := actions[rng.Intn(len(actions))]
 		game.TakeAction(randomAction)
 		if game.IsOver() {
 			break
@@ -0,0 +1,28 @@
+/*
+	The ratings command prints the leaderboard of a ratings file, which the
+	tournament command updates with -ratings:
+
+		ratings ratings.json
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/midrange/rogue/rating"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: ratings ratings.json")
+		os.Exit(2)
+	}
+	ledger, err := rating.Load(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	ledger.WriteLeaderboard(os.Stdout)
+}
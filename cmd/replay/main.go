@@ -0,0 +1,97 @@
+/*
+	The replay command steps through a game saved with play -record, forward
+	and backward, showing the board and what each action did:
+
+		replay game.json
+
+	With -events it prints everything that happened in the game instead.
+*/
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/midrange/rogue/game"
+)
+
+var printEvents = flag.Bool("events", false, "print the event log of the game instead of stepping through it")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: replay [flags] game.json\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	replay, err := game.LoadReplay(flag.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	games, events, err := replay.Play()
+	if err != nil {
+		// show as much of the game as could be replayed
+		fmt.Println(err)
+	}
+	fmt.Printf("%s on the play against %s, seed %d\n", replay.Strategies[0], replay.Strategies[1], replay.Seed)
+
+	if *printEvents {
+		for _, stepEvents := range events {
+			for _, e := range stepEvents {
+				fmt.Println(e)
+			}
+		}
+		return
+	}
+	step(games, events)
+}
+
+// step shows the game after each action, and reads where to go next.
+func step(games []*game.Game, events [][]*game.Event) {
+	reader := bufio.NewReader(os.Stdin)
+	i := 0
+	for {
+		g := games[i]
+		g.Print()
+		if i > 0 {
+			for _, e := range events[i-1] {
+				fmt.Println(e)
+			}
+		}
+		fmt.Printf("\nAction %d of %d, turn %d %s", i, len(games)-1, g.Turn, g.Phase)
+		if g.IsOver() {
+			fmt.Print(", the game is over")
+		}
+		fmt.Print("\nEnter for the next action, b to go back, a number to go to that action, or q to quit: ")
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		text = strings.TrimSpace(text)
+		switch {
+		case text == "" || text == "n":
+			if i < len(games)-1 {
+				i++
+			}
+		case text == "b":
+			if i > 0 {
+				i--
+			}
+		case text == "q":
+			return
+		default:
+			if n, err := strconv.Atoi(text); err == nil && n >= 0 && n < len(games) {
+				i = n
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+/*
+	The tournament command plays strategies and decks against each other
+	without anyone watching, and reports how often each one wins:
+
+		tournament -games 100 -playouts 200 mcst:delver attack:stompy random:stompy
+
+	Each argument is an entry, a strategy and a deck separated by a colon.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/midrange/rogue/game"
+	"github.com/midrange/rogue/rating"
+	"github.com/midrange/rogue/tournament"
+)
+
+var format = flag.String("format", "roundrobin", "roundrobin to pair every entry with every other one, or gauntlet to pair the first entry with each of the others")
+var games = flag.Int("games", 20, "how many games each pairing plays, half with each entry on the play")
+var parallel = flag.Int("parallel", 0, "how many games to play at once, the number of CPUs by default")
+var seed = flag.Int64("seed", 0, "the seed of the tournament, from the clock by default")
+var jsonPath = flag.String("json", "", "a file to write the results to as JSON, or - for standard output")
+var csvPath = flag.String("csv", "", "a file to write the results to as CSV, or - for standard output")
+var ratingsPath = flag.String("ratings", "", "a ratings file to update with the results, and print the leaderboard of")
+
+// Parameters for the bots.
+var exploration = flag.Float64("c", game.DefaultBotConfig(0).C, "the exploration constant of mcst")
+var calculationTime = flag.Float64("time", 1, "how many seconds mcst thinks for each action")
+var playouts = flag.Int("playouts", 0, "if positive, how many playouts mcst does for each action instead of thinking for -time")
+var workers = flag.Int("workers", 1, "how many goroutines each mcst searches with")
+var command = flag.String("command", "", "the program that the external strategy runs, with its arguments")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: tournament [flags] strategy:deck strategy:deck...\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Strategies: %s\n", strings.Join(game.StrategyNames(), ", "))
+		fmt.Fprintf(flag.CommandLine.Output(), "Decks: %s, or a text decklist file\n\n", strings.Join(game.DeckNames(), ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	config := tournament.Config{
+		Games:    *games,
+		Parallel: *parallel,
+		Seed:     *seed,
+		Bot: game.BotConfig{
+			C:               *exploration,
+			CalculationTime: *calculationTime,
+			Playouts:        *playouts,
+			Workers:         *workers,
+			Command:         *command,
+		},
+	}
+	var err error
+	if config.Format, err = tournament.ParseFormat(*format); err != nil {
+		exit(err)
+	}
+	for _, arg := range flag.Args() {
+		entry, err := tournament.ParseEntry(arg)
+		if err != nil {
+			exit(err)
+		}
+		config.Entries = append(config.Entries, entry)
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	fmt.Printf("Seed: %d\n\n", config.Seed)
+
+	start := time.Now()
+	pairings, results, err := tournament.Run(config)
+	if err != nil {
+		exit(err)
+	}
+	tournament.WriteTable(os.Stdout, pairings)
+	fmt.Printf("\nPlayed %d games in %s\n", len(results), time.Since(start).Round(time.Millisecond))
+
+	if *jsonPath != "" {
+		write(*jsonPath, func(f *os.File) error { return tournament.WriteJSON(f, pairings, results) })
+	}
+	if *csvPath != "" {
+		write(*csvPath, func(f *os.File) error { return tournament.WriteCSV(f, pairings) })
+	}
+	if *ratingsPath != "" {
+		updateRatings(config, results)
+	}
+}
+
+// updateRatings records the games in the ratings file, in the order they were
+// scheduled, and prints the leaderboard.
+func updateRatings(config tournament.Config, results []*tournament.GameResult) {
+	ledger, err := rating.Load(*ratingsPath)
+	if err != nil {
+		exit(err)
+	}
+	keys := map[tournament.Entry]string{}
+	for _, e := range config.Entries {
+		strategy, _ := game.NewStrategy(e.Strategy, config.Bot)
+		keys[e] = rating.Key(strategy, e.Deck)
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		onThePlay, onTheDraw := keys[r.A], keys[r.B]
+		winner := game.NoPlayerId
+		switch {
+		case r.Winner == "A" && r.AOnThePlay, r.Winner == "B" && !r.AOnThePlay:
+			winner = game.OnThePlay
+		case r.Winner != "draw":
+			winner = game.OnTheDraw
+		}
+		if !r.AOnThePlay {
+			onThePlay, onTheDraw = onTheDraw, onThePlay
+		}
+		ledger.RecordGame(onThePlay, onTheDraw, winner)
+	}
+	if err := ledger.Save(*ratingsPath); err != nil {
+		exit(err)
+	}
+	fmt.Println()
+	ledger.WriteLeaderboard(os.Stdout)
+}
+
+// write writes a report to the file at path, or to standard output for "-".
+func write(path string, report func(f *os.File) error) {
+	f := os.Stdout
+	if path != "-" {
+		var err error
+		if f, err = os.Create(path); err != nil {
+			exit(err)
+		}
+		defer f.Close()
+	}
+	if err := report(f); err != nil {
+		exit(err)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -14,14 +14,16 @@ type Action struct {
 	// the spell target Card's coming into play effect
 	EntersTheBattleFieldSpellTarget StackObjectId
 	Cost                            *Cost
+	// for mana abilities, the type of mana to make
+	Color Color
 	// for non-targeted effects, such as in Snap
 	Selected []PermanentId
 	// whether to switch priority after the action
 	ShouldSwitchPriority bool
-	// for targeted effects
-	Source      PermanentId
-	SpellTarget StackObjectId
-	Target      PermanentId
+	// the permanent with the ability being activated or used for mana
+	Source PermanentId
+	// for targeted effects, and for blocking, the attacker to block
+	Target Target
 	// for attacking
 	With          PermanentId
 	WithAlternate bool
@@ -46,15 +48,14 @@ const (
 	MakeChoice
 	PassPriority
 	UseForMana
+	Discard
+	FinishDeclaration
+	KeepHand
+	TakeMulligan
+	PutOnBottom
+	BeginningOfEndStepEffect
 )
 
-func (a *Action) targetPronoun(p *Player) string {
-	if p.game.Permanent(a.Target).Owner == p.Id {
-		return "your"
-	}
-	return "their"
-}
-
 // For debugging and logging. Don't use this in the critical path.
 func (a *Action) ShowTo(p *Player) string {
 	forHuman := false
@@ -63,11 +64,7 @@ func (a *Action) ShowTo(p *Player) string {
 		if len(p.game.Stack) > 0 {
 			return fmt.Sprintf("%s", p.game.StackObject(p.game.Stack[len(p.game.Stack)-1]))
 		}
-		if p.game.Phase == Upkeep ||
-			p.game.Phase == Draw ||
-			p.game.Phase == CombatDamage ||
-			p.game.Phase == Main1 ||
-			p.game.Phase == Main2 {
+		if p.game.Phase.HasPriorityWindow() || p.game.DeclarationFinished {
 			return fmt.Sprintf("end %s", p.game.Phase)
 		}
 		return "Pass priority"
@@ -75,13 +72,12 @@ func (a *Action) ShowTo(p *Player) string {
 		if len(p.game.Stack) > 0 {
 			return fmt.Sprintf("resolve %s", p.game.StackObject(p.game.Stack[len(p.game.Stack)-1]))
 		}
-		if p.game.Phase == Upkeep ||
-			p.game.Phase == Draw ||
-			p.game.Phase == CombatDamage ||
-			p.game.Phase == Main1 ||
-			p.game.Phase == Main2 {
+		if p.game.Phase.HasPriorityWindow() || p.game.DeclarationFinished {
 			return fmt.Sprintf("agree to end %s", p.game.Phase)
 		}
+		if p.game.Phase == Cleanup {
+			return "end turn"
+		}
 		return "Pass"
 	case ChooseTargetAndMana:
 		forHuman = true
@@ -91,30 +87,30 @@ func (a *Action) ShowTo(p *Player) string {
 			return fmt.Sprintf("%s: %s", a.Card.Ninjitsu, a.Card)
 		}
 		if a.WithAlternate {
-			if a.Target == NoPermanentId {
+			if a.Target == NoTarget {
 				return fmt.Sprintf("%s: %s", a.Card.AlternateCastingCost, a.Card)
 			}
-			return fmt.Sprintf("%s: %s on %s %s",
-				a.Card.AlternateCastingCost, a.Card, a.targetPronoun(p), a.Target)
+			return fmt.Sprintf("%s: %s on %s",
+				a.Card.AlternateCastingCost, a.Card, a.Target.ShowTo(p))
 		}
 		if a.WithPhyrexian {
-			if a.Target == NoPermanentId {
+			if a.Target == NoTarget {
 				return fmt.Sprintf("%s: %s", a.Card.PhyrexianCastingCost, a.Card)
 			}
-			return fmt.Sprintf("%s: %s on %s %s",
-				a.Card.PhyrexianCastingCost, a.Card, a.targetPronoun(p), p.game.Permanent(a.Target))
+			return fmt.Sprintf("%s: %s on %s",
+				a.Card.PhyrexianCastingCost, a.Card, a.Target.ShowTo(p))
 		}
 		if a.WithKicker {
-			if a.Target == NoPermanentId {
-				return fmt.Sprintf("%s: %s with kicker", a.Card.Kicker.Cost, a.Card)
+			if a.Target == NoTarget {
+				return fmt.Sprintf("%s: %s with kicker", a.Card.KickedCost(), a.Card)
 			}
-			return fmt.Sprintf("%s: %s on %s %s with kicker",
-				a.Card.Kicker.Cost, a.Card, a.targetPronoun(p), p.game.Permanent(a.Target))
+			return fmt.Sprintf("%s: %s on %s with kicker",
+				a.Card.KickedCost(), a.Card, a.Target.ShowTo(p))
 		}
 		if a.Card.IsLand() {
 			return fmt.Sprintf("%s", a.Card)
 		}
-		if a.Target == NoPermanentId {
+		if a.Target == NoTarget {
 			if forHuman && (a.Card.AlternateCastingCost != nil || a.Card.PhyrexianCastingCost != nil) {
 				return fmt.Sprintf("%s", a.Card)
 			}
@@ -125,19 +121,32 @@ func (a *Action) ShowTo(p *Player) string {
 			for _, perm := range a.Selected {
 				cardNames = append(cardNames, fmt.Sprintf("%s", p.game.Permanent(perm).Card.Name))
 			}
-			return fmt.Sprintf("%s: %s on %s %s (%s)",
-				a.Card.CastingCost, a.Card, a.targetPronoun(p), p.game.Permanent(a.Target), strings.Join(cardNames, ", "))
+			return fmt.Sprintf("%s: %s on %s (%s)",
+				a.Card.CastingCost, a.Card, a.Target.ShowTo(p), strings.Join(cardNames, ", "))
 		}
-		return fmt.Sprintf("%s: %s on %s %s",
-			a.Card.CastingCost, a.Card, a.targetPronoun(p), p.game.Permanent(a.Target))
+		return fmt.Sprintf("%s: %s on %s",
+			a.Card.CastingCost, a.Card, a.Target.ShowTo(p))
 	case Attack:
 		return fmt.Sprintf("Attack with %s", p.game.Permanent(a.With))
 	case Block:
-		return fmt.Sprintf("%s blocks %s", p.game.Permanent(a.With), p.game.Permanent(a.Target))
+		return fmt.Sprintf("%s blocks %s", p.game.Permanent(a.With), p.game.Permanent(a.Target.Permanent))
 	case UseForMana:
-		return fmt.Sprintf("Tap %s for mana", p.game.Permanent(a.Source))
+		return fmt.Sprintf("Tap %s for %s", p.game.Permanent(a.Source), a.Color.Symbol())
 	case Activate:
 		return fmt.Sprintf("Use %s", p.game.Permanent(a.Source))
+	case Discard:
+		return fmt.Sprintf("Discard %s", a.Card)
+	case KeepHand:
+		return fmt.Sprintf("Keep %d cards", len(p.Hand))
+	case TakeMulligan:
+		return fmt.Sprintf("Mulligan to %d", OpeningHandSize-p.Mulligans-1)
+	case PutOnBottom:
+		return fmt.Sprintf("Put %s on the bottom", a.Card)
+	case FinishDeclaration:
+		if p.game.Phase == DeclareAttackers {
+			return "Done declaring attackers"
+		}
+		return "Done declaring blockers"
 	case MakeChoice:
 		if a.AfterEffect.EffectType == ReturnScryCardsDraw {
 			topStrings := []string{}
@@ -4,9 +4,9 @@ package game
 
 import "strconv"
 
-const _ActionType_name = "PassPlayActivateAttackBlockChooseTargetAndManaDecideOnChoiceDeclineChoiceEntersTheBattlefieldEffectMakeChoicePassPriorityUseForMana"
+const _ActionType_name = "PassPlayActivateAttackBlockChooseTargetAndManaDecideOnChoiceDeclineChoiceEntersTheBattlefieldEffectMakeChoicePassPriorityUseForManaDiscardFinishDeclarationKeepHandTakeMulliganPutOnBottomBeginningOfEndStepEffect"
 
-var _ActionType_index = [...]uint8{0, 4, 8, 16, 22, 27, 46, 60, 73, 99, 109, 121, 131}
+var _ActionType_index = [...]uint8{0, 4, 8, 16, 22, 27, 46, 60, 73, 99, 109, 121, 131, 138, 155, 163, 175, 186, 210}
 
 func (i ActionType) String() string {
 	if i < 0 || i >= ActionType(len(_ActionType_index)-1) {
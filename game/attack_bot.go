@@ -26,7 +26,7 @@ func (ab *AttackBot) Action(g *Game) *Action {
 				continue
 			}
 			if bestAction.Type == Play {
-				if a.Card.CastingCost != nil && bestAction.Card.CastingCost != nil && a.Card.CastingCost.Colorless > bestAction.Card.CastingCost.Colorless {
+				if a.Card.CastingCost != nil && bestAction.Card.CastingCost != nil && a.Card.CastingCost.ConvertedManaCost() > bestAction.Card.CastingCost.ConvertedManaCost() {
 					bestAction = a
 				}
 			} else {
@@ -36,7 +36,7 @@ func (ab *AttackBot) Action(g *Game) *Action {
 	}
 	for _, a := range actions {
 		if a.Type == Play && a.Card.IsCreature() {
-			if a.Card.CastingCost != nil && bestAction.Card.CastingCost != nil && a.Card.CastingCost.Colorless > bestAction.Card.CastingCost.Colorless {
+			if a.Card.CastingCost != nil && bestAction.Card.CastingCost != nil && a.Card.CastingCost.ConvertedManaCost() > bestAction.Card.CastingCost.ConvertedManaCost() {
 				bestAction = a
 			}
 		}
@@ -72,10 +72,10 @@ func (ab *AttackBot) Action(g *Game) *Action {
 
 func (a *Action) isOpponentBuff(g *Game) bool {
 	c := a.Card
-	if a.Target == NoPermanentId {
+	if c == nil || a.Target.Type != TargetPermanent {
 		return false
 	}
-	target := g.Permanent(a.Target)
+	target := g.Permanent(a.Target.Permanent)
 	return target.Owner != g.PriorityId && (c.Name == Rancor || c.Name == VinesOfVastwood ||
 		c.Name == MutagenicGrowth || c.Name == HungerOfTheHowlpack)
 }
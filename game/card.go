@@ -4,11 +4,12 @@ import ()
 
 // Card should be treated as immutable.
 // The properties on Card are the properties like "base toughness" that do not change
-// over time for a particular card.
+// over time for a particular card. They are loaded from cards.json.
 type Card struct {
 	ActivatedAbility            *Effect
 	AddsTemporaryEffect         bool
 	AlternateCastingCost        *Cost
+	BeginningOfEndStepEffect    *Effect // at the beginning of your end step
 	BeginningOfYourUpkeepEffect *Effect
 	Bloodthirst                 int
 	CastingCost                 *Cost
@@ -27,6 +28,7 @@ type Card struct {
 	Name                        CardName
 	Ninjitsu                    *Cost
 
+	// Set from CastingCost for cards with Phyrexian mana, to cast them paying life.
 	PhyrexianCastingCost *Cost
 	Powermenace          bool // only blockable by >= power (like Skarrgan Pitskulk)
 
@@ -43,518 +45,60 @@ type Card struct {
 	// For flip cards like Delver of Secrets.
 	TransformInto CardName
 
-	// Properties that are relevant for Lands and other mana producers
-	Colorless         int
+	// Tokens are not cards, so they cease to exist when they leave the battlefield.
+	Token bool
+
+	// Text is the rules text of the card, for people to read.
+	Text string
+
+	// Properties that are relevant for Lands and other mana producers.
+	// Produces is the types of mana it can make, one of them each time it is used.
+	Produces          []Color
 	SacrificesForMana bool
 
 	// Properties that are relevant for Auras
 	EnchantedPermanentDiesEffect *Effect
 }
 
-//go:generate stringer -type=CardName
-type CardName int
+// A CardName is the name printed on a card, like "Delver of Secrets".
+type CardName string
 
-// Keep NoCard first, the rest in alphabetical order.
+// Cards that Go code refers to by name. Other cards only need to be in
+// cards.json.
 const (
-	NoCard CardName = iota
-
-	BurningTreeEmissary
-	Counterspell
-	Daze
-	DelverOfSecrets
-	EldraziSpawnToken
-	ElephantGuide
-	ElephantToken
-	FaerieMiscreant
-	Forest
-	GrizzlyBears
-	Gush
-	HungerOfTheHowlpack
-	InsectileAberration
-	Island
-	MutagenicGrowth
-	NestInvader
-	NettleSentinel
-	NinjaOfTheDeepHours
-	Ponder
-	Preordain
-	QuirionRanger
-	Rancor
-	SilhanaLedgewalker
-	SkarrganPitskulk
-	Snap
-	SpellstutterSprite
-	VaultSkirge
-	VinesOfVastwood
+	NoCard CardName = ""
+
+	BurningTreeEmissary CardName = "Burning-Tree Emissary"
+	Counterspell        CardName = "Counterspell"
+	Daze                CardName = "Daze"
+	DelverOfSecrets     CardName = "Delver of Secrets"
+	EldraziSpawnToken   CardName = "Eldrazi Spawn Token"
+	ElephantGuide       CardName = "Elephant Guide"
+	ElephantToken       CardName = "Elephant Token"
+	FaerieMiscreant     CardName = "Faerie Miscreant"
+	Forest              CardName = "Forest"
+	GrizzlyBears        CardName = "Grizzly Bears"
+	Gush                CardName = "Gush"
+	HungerOfTheHowlpack CardName = "Hunger of the Howlpack"
+	InsectileAberration CardName = "Insectile Aberration"
+	Island              CardName = "Island"
+	Mountain            CardName = "Mountain"
+	MutagenicGrowth     CardName = "Mutagenic Growth"
+	NestInvader         CardName = "Nest Invader"
+	NettleSentinel      CardName = "Nettle Sentinel"
+	NinjaOfTheDeepHours CardName = "Ninja of the Deep Hours"
+	Ponder              CardName = "Ponder"
+	Preordain           CardName = "Preordain"
+	QuirionRanger       CardName = "Quirion Ranger"
+	Rancor              CardName = "Rancor"
+	SilhanaLedgewalker  CardName = "Silhana Ledgewalker"
+	SkarrganPitskulk    CardName = "Skarrgan Pit-Skulk"
+	Snap                CardName = "Snap"
+	SpellstutterSprite  CardName = "Spellstutter Sprite"
+	VaultSkirge         CardName = "Vault Skirge"
+	VinesOfVastwood     CardName = "Vines of Vastwood"
 )
 
-var Cards = map[CardName]*Card{
-
-	/*
-		Creature — Human Shaman
-		When Burning-Tree Emissary enters the battlefield, add RG.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=366467
-	*/
-	BurningTreeEmissary: &Card{
-		BasePower:                  2,
-		BaseToughness:              2,
-		CastingCost:                &Cost{Colorless: 2},
-		EntersTheBattlefieldEffect: &Effect{Colorless: 2, EffectType: AddMana},
-		Type: []Type{Creature},
-	},
-
-	/*
-		Counter target spell.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=202437
-	*/
-	Counterspell: &Card{
-		CastingCost: &Cost{Colorless: 2},
-		Effects: []*Effect{
-			&Effect{
-				EffectType: Countermagic,
-				Selector:   &Selector{Type: Spell},
-			},
-		},
-		Type: []Type{Instant},
-	},
-
-	/*
-		You may return an Island you control to its owner's hand rather than pay
-		this spell's mana cost.
-
-		Counter target spell unless its controller pays 1.
-
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=21284
-	*/
-	Daze: &Card{
-		AlternateCastingCost: &Cost{
-			Effect: &Effect{
-				EffectType: ReturnToHand,
-				Selector:   &Selector{Subtype: LandIsland, ControlledBy: SamePlayer, Count: 1, Targeted: false},
-			},
-		},
-		CastingCost: &Cost{Colorless: 2},
-		Effects: []*Effect{&Effect{
-			EffectType: ManaSink,
-			Selector:   &Selector{Type: Spell, Count: 1},
-		}},
-		Type: []Type{Instant},
-	},
-
-	/*
-		At the beginning of your upkeep, look at the top card of your library.
-		You may reveal that card. If an instant or sorcery card is revealed this way,
-		transform Delver of Secrets.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?name=delver+of+secrets
-	*/
-	DelverOfSecrets: &Card{
-		BasePower:     1,
-		BaseToughness: 1,
-		BeginningOfYourUpkeepEffect: &Effect{
-			EffectType: DelverScry,
-		},
-		CastingCost:   &Cost{Colorless: 1},
-		TransformInto: InsectileAberration,
-		Type:          []Type{Creature},
-	},
-
-	/*
-		Created by NestInvader.
-	*/
-	EldraziSpawnToken: &Card{
-		BasePower:         0,
-		BaseToughness:     1,
-		CastingCost:       &Cost{Colorless: 0},
-		Colorless:         1,
-		SacrificesForMana: true,
-		Type:              []Type{Creature},
-	},
-
-	/*
-
-		Enchanted creature gets +3/+3.
-		When enchanted creature dies, create a 3/3 green Elephant creature token.
-	*/
-	ElephantGuide: &Card{
-		BasePower:                    3,
-		BaseToughness:                3,
-		CastingCost:                  &Cost{Colorless: 3},
-		EnchantedPermanentDiesEffect: &Effect{Summon: ElephantToken},
-		Selector:                     &Selector{Type: Creature},
-		Type:                         []Type{Enchantment},
-	},
-
-	/*
-		Created by NestInvader.
-	*/
-	ElephantToken: &Card{
-		BasePower:     3,
-		BaseToughness: 3,
-		CastingCost:   &Cost{Colorless: 0},
-		Type:          []Type{Creature},
-	},
-
-	/*
-		Flying (This creature can't be blocked except by creatures with flying or reach.)
-		When Faerie Miscreant enters the battlefield, if you control another creature
-		named Faerie Miscreant, draw a card.
-	*/
-	FaerieMiscreant: &Card{
-		BasePower:                  1,
-		BaseToughness:              1,
-		CastingCost:                &Cost{Colorless: 1},
-		EntersTheBattlefieldEffect: &Effect{Condition: &Condition{ControlAnother: FaerieMiscreant}, EffectType: DrawCard},
-		Flying:  true,
-		Subtype: []Subtype{Faerie},
-		Type:    []Type{Creature},
-	},
-
-	/*
-		G
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=443154
-	*/
-	Forest: &Card{
-		Colorless: 1,
-		Subtype:   []Subtype{LandForest},
-		Supertype: []Supertype{Basic},
-		Type:      []Type{Land},
-	},
-
-	/*
-		No card text.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=4300
-	*/
-	GrizzlyBears: &Card{
-		BasePower:     2,
-		BaseToughness: 2,
-		CastingCost:   &Cost{Colorless: 2},
-		Type:          []Type{Creature},
-	},
-
-	/*
-		You may return two Islands you control to their owner's hand rather than pay this spell's mana cost.
-		Draw two cards.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=20404
-	*/
-	Gush: &Card{
-		AlternateCastingCost: &Cost{
-			Effect: &Effect{
-				EffectType: ReturnToHand,
-				Selector:   &Selector{Subtype: LandIsland, ControlledBy: SamePlayer, Count: 2},
-			},
-		},
-		CastingCost: &Cost{Colorless: 5},
-		Effects: []*Effect{&Effect{
-			EffectType: DrawCard,
-			Selector:   &Selector{Count: 2},
-		}},
-		Type: []Type{Instant},
-	},
-
-	/*
-		Put a +1/+1 counter on target creature.
-		Morbid - Put three +1/+1 counters on that creature instead if a creature died
-		this turn.
-	*/
-	HungerOfTheHowlpack: &Card{
-		Effects: []*Effect{&Effect{
-			Plus1Plus1Counters: 1,
-		}},
-		CastingCost: &Cost{Colorless: 1},
-		Morbid: &Effect{
-			Plus1Plus1Counters: 2,
-		},
-		Type: []Type{Instant},
-	},
-
-	/*
-		Transformed from DelverOfSecrets.
-		Flying
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?name=delver+of+secrets
-	*/
-	InsectileAberration: &Card{
-		BasePower:     3,
-		BaseToughness: 2,
-		CastingCost:   &Cost{Colorless: 0},
-		Flying:        true,
-		IsTransformed: true,
-		TransformInto: DelverOfSecrets,
-		Type:          []Type{Creature},
-	},
-
-	/*
-		U
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?name=ISLAND
-	*/
-	Island: &Card{
-		Colorless: 1,
-		Subtype:   []Subtype{LandIsland},
-		Supertype: []Supertype{Basic},
-		Type:      []Type{Land},
-	},
-
-	/*
-		(Phyrexian Green can be paid with either Green or 2 life.)
-		Target creature gets +2/+2 until end of turn.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?name=mutagenic%20growth
-	*/
-	MutagenicGrowth: &Card{
-		AddsTemporaryEffect: true,
-		CastingCost:         &Cost{Colorless: 1},
-		Effects: []*Effect{&Effect{
-			Power:     2,
-			Selector:  &Selector{Type: Creature},
-			Toughness: 2,
-		}},
-		PhyrexianCastingCost: &Cost{Life: 2},
-		Type:                 []Type{Instant},
-	},
-
-	/*
-		Creature — Eldrazi Drone
-		When Nest Invader enters the battlefield, create a 0/1 colorless
-		Eldrazi Spawn creature token. It has "Sacrifice this creature:
-		Add (1)."
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=193420
-	*/
-	NestInvader: &Card{
-		BasePower:                  2,
-		BaseToughness:              2,
-		CastingCost:                &Cost{Colorless: 2},
-		EntersTheBattlefieldEffect: &Effect{Summon: EldraziSpawnToken},
-		Type: []Type{Creature},
-	},
-
-	/*
-		Nettle Sentinel doesn't untap during your untap step.
-		Whenever you cast a green spell, you may untap Nettle Sentinel.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=442171
-	*/
-	NettleSentinel: &Card{
-		BasePower:     2,
-		BaseToughness: 2,
-		CastingCost:   &Cost{Colorless: 1},
-		Type:          []Type{Creature},
-	},
-
-	/*
-		Ninjutsu 1Blue (1Blue, Return an unblocked attacker you control to hand: Put this card onto
-		the battlefield from your hand tapped and attacking.)
-		Whenever Ninja of the Deep Hours deals combat damage to a player, you may draw a card.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=74587
-	*/
-	NinjaOfTheDeepHours: &Card{
-		BasePower:               2,
-		BaseToughness:           2,
-		CastingCost:             &Cost{Colorless: 4},
-		DealsCombatDamageEffect: &Effect{EffectType: DrawCard, Selector: &Selector{Count: 1}},
-		Ninjitsu: &Cost{
-			Colorless: 2,
-			Effect: &Effect{
-				EffectType: ReturnToHand,
-				Selector: &Selector{
-					Type:         Creature,
-					ControlledBy: SamePlayer,
-					Targeted:     false,
-					AttackStatus: Unblocked},
-			}},
-		Type: []Type{Creature},
-	},
-
-	/*
-		Look at the top three cards of your library, then put them back in any order.
-		You may shuffle your library.
-		Draw a card.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?name=ponder
-	*/
-	Ponder: &Card{
-		CastingCost: &Cost{Colorless: 1},
-		Effects: []*Effect{
-			&Effect{
-				EffectType: TopScryDraw,
-				Selector:   &Selector{Count: 3},
-			},
-		},
-		Type: []Type{Sorcery},
-	},
-
-	/*
-		Scry 2, then draw a card. (To scry 2, look at the top two cards of your library,
-		then put any number of them on the bottom of your library and the rest on top in any order.)
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?name=preordain
-	*/
-	Preordain: &Card{
-		CastingCost: &Cost{Colorless: 1},
-		Effects: []*Effect{
-			&Effect{
-				EffectType: ScryDraw,
-				Selector:   &Selector{Count: 2},
-			},
-		},
-		Type: []Type{Sorcery},
-	},
-
-	/*
-		Creature — Elf
-		Return a Forest you control to its owner's hand: Untap target creature.
-		Activate this ability only once each turn.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=3674
-	*/
-	QuirionRanger: &Card{
-		ActivatedAbility: &Effect{
-			Cost: &Cost{
-				Effect: &Effect{
-					EffectType: ReturnToHand,
-					Selector:   &Selector{Subtype: LandForest, ControlledBy: SamePlayer, Targeted: false},
-				},
-			},
-			EffectType: Untap,
-			Selector:   &Selector{Type: Creature},
-		},
-		BasePower:     1,
-		BaseToughness: 1,
-		CastingCost:   &Cost{Colorless: 1},
-		Type:          []Type{Creature},
-	},
-
-	/*
-		Enchanted creature gets +2/+0 and has trample.
-		When Rancor is put into a graveyard from the battlefield,
-		return Rancor to its owner's hand.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=442175
-	*/
-	Rancor: &Card{
-		BasePower:     2,
-		BaseToughness: 0,
-		CastingCost:   &Cost{Colorless: 1},
-		EntersGraveyardEffect: &Effect{
-			EffectType: ReturnToHand,
-		},
-		Selector: &Selector{Type: Creature},
-		Type:     []Type{Enchantment},
-	},
-
-	/*
-		Creature — Elf Rogue
-		Hexproof (This creature can't be the target of spells or abilities your
-		opponents control.)
-		Silhana Ledgewalker can't be blocked except by creatures with flying.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=423502
-	*/
-	SilhanaLedgewalker: &Card{
-		BasePower:     1,
-		BaseToughness: 1,
-		CastingCost:   &Cost{Colorless: 2},
-		Hexproof:      true,
-		GroundEvader:  true,
-		Type:          []Type{Creature},
-	},
-
-	/*
-		Creature — Human Warrior
-		Bloodthirst 1 (If an opponent was dealt damage this turn, this creature enters
-		the battlefield with a +1/+1 counter on it.)
-		Creatures with power less than Skarrgan Pit-Skulk's power can't block it.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=426622
-	*/
-	SkarrganPitskulk: &Card{
-		BasePower:     1,
-		BaseToughness: 1,
-		Bloodthirst:   1,
-		CastingCost:   &Cost{Colorless: 1},
-		Powermenace:   true,
-		Type:          []Type{Creature},
-	},
-
-	/*
-		Return target creature to its owner's hand. Untap up to two lands.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?name=snap
-	*/
-	Snap: &Card{
-		CastingCost: &Cost{Colorless: 2},
-		Effects: []*Effect{
-			&Effect{
-				EffectType: Untap,
-				Selector:   &Selector{Type: Land, Count: 2, Targeted: false},
-			},
-			&Effect{
-				EffectType: ReturnToHand,
-				Selector:   &Selector{Type: Creature, Targeted: true},
-			},
-		},
-		Type: []Type{Instant},
-	},
-
-	/*
-		Flash
-		Flying
-		When Spellstutter Sprite enters the battlefield, counter target spell with converted mana
-		cost X or less, where X is the number of Faeries you control.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=139429
-	*/
-	SpellstutterSprite: &Card{
-		BasePower:     1,
-		BaseToughness: 1,
-		CastingCost:   &Cost{Colorless: 2},
-		EntersTheBattlefieldEffect: &Effect{
-			EffectType: Countermagic,
-			Condition:  &Condition{ConvertedManaCostLTE: Faerie},
-			Selector:   &Selector{Type: Spell},
-		},
-		Flash:   true,
-		Flying:  true,
-		Subtype: []Subtype{Faerie},
-		Type:    []Type{Creature},
-	},
-
-	/*
-
-		Artifact Creature — Imp
-		(Phyrexian Black can be paid with either Black or 2 life.)
-		Flying
-		Lifelink (Damage dealt by this creature also causes you to gain that much life.)
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=217984
-	*/
-	VaultSkirge: &Card{
-		BasePower:            1,
-		BaseToughness:        1,
-		CastingCost:          &Cost{Colorless: 2},
-		Flying:               true,
-		Lifelink:             true,
-		PhyrexianCastingCost: &Cost{Life: 2, Colorless: 1},
-		Type:                 []Type{Artifact, Creature},
-	},
-
-	/*
-		Kicker Green (You may pay an additional Green as you cast this spell.)
-		Target creature can't be the target of spells or abilities your opponents
-		control this turn. If this spell was kicked, that creature gets +4/+4 until
-		end of turn.
-		http://gatherer.wizards.com/Pages/Card/Details.aspx?multiverseid=397747
-	*/
-	VinesOfVastwood: &Card{
-		AddsTemporaryEffect: true,
-		CastingCost:         &Cost{Colorless: 1},
-		Kicker: &Effect{
-			Cost:      &Cost{Colorless: 2},
-			Power:     4,
-			Selector:  &Selector{Type: Creature, Targeted: true},
-			Toughness: 4,
-		},
-		Effects: []*Effect{&Effect{
-			Selector:     &Selector{Type: Creature, Targeted: true},
-			Untargetable: true,
-		}},
-		Type: []Type{Instant},
-	},
-}
-
-func init() {
-	for name, card := range Cards {
-		card.Name = name
-	}
-}
-
 func (cn CardName) Card() *Card {
 	return Cards[cn]
 }
@@ -564,6 +108,12 @@ func (c *Card) String() string {
 	return p.String()
 }
 
+// KickedCost is what casting the card with its kicker costs, which is the
+// kicker on top of the casting cost.
+func (c *Card) KickedCost() *Cost {
+	return c.CastingCost.Plus(c.Kicker.Cost)
+}
+
 func (c *Card) IsCreature() bool {
 	for _, t := range c.Type {
 		if t == Creature {
@@ -626,6 +176,15 @@ func (c *Card) HasSubtype(subtype Subtype) bool {
 	return false
 }
 
+func (c *Card) HasSupertype(supertype Supertype) bool {
+	for _, st := range c.Supertype {
+		if st == supertype {
+			return true
+		}
+	}
+	return false
+}
+
 // TODO this should check kicker too
 func (c *Card) HasCreatureTargets() bool {
 	if c.Selector != nil {
@@ -643,6 +202,22 @@ func (c *Card) HasCreatureTargets() bool {
 	return false
 }
 
+func (c *Card) HasPlayerTargets() bool {
+	if c.Selector != nil {
+		if c.Selector.Type == AnyPlayer {
+			return true
+		}
+	}
+	for _, e := range c.Effects {
+		if e.Selector != nil {
+			if e.Selector.Type == AnyPlayer {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (c *Card) HasSpellTargets() bool {
 	if c.Selector != nil {
 		if c.Selector.Type == Spell {
@@ -667,3 +242,12 @@ func (c *Card) HasEntersTheBattlefieldTargets() bool {
 	}
 	return false
 }
+
+// FrontFace is the card that a permanent is in every zone other than the
+// battlefield, like Delver of Secrets for a flipped Insectile Aberration.
+func (c *Card) FrontFace() CardName {
+	if c.IsTransformed {
+		return c.TransformInto
+	}
+	return c.Name
+}
@@ -0,0 +1,446 @@
+/*
+	The cards are defined in cards.json, which is built into the program and
+	loaded into Cards at startup.
+
+	Each card in cards.json has the same fields as Card, Effect, Selector and
+	Condition, with a few differences so that the file is easy to write by hand:
+
+	- Enums are written by name, like "Creature" or "ReturnToHand".
+	- Costs are written like "1U", "(R/G)" for a hybrid symbol and "(G/P)" for a
+	  Phyrexian symbol. A Cost with an Effect or Life is written as an object,
+	  like {"Mana": "1U", "Effect": {...}}.
+	- Cards refer to other cards by name, like "TransformInto": "Insectile Aberration".
+
+	Fields that are left out have their zero value, like in a Go literal.
+	Unknown fields, enum names, costs and card names are errors.
+*/
+
+package game
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+//go:embed cards.json
+var cardsJSON []byte
+
+var Cards = mustLoadCards(cardsJSON)
+
+func mustLoadCards(data []byte) map[CardName]*Card {
+	cards, err := LoadCards(data)
+	if err != nil {
+		panic(err)
+	}
+	return cards
+}
+
+type cardData struct {
+	Name      CardName
+	Text      string
+	Type      []string
+	Subtype   []string
+	Supertype []string
+
+	CastingCost   *costData
+	BasePower     int
+	BaseToughness int
+
+	ActivatedAbility             *effectData
+	AddsTemporaryEffect          bool
+	AlternateCastingCost         *costData
+	BaseTrample                  bool
+	BeginningOfEndStepEffect     *effectData
+	BeginningOfYourUpkeepEffect  *effectData
+	Bloodthirst                  int
+	DealsCombatDamageEffect      *effectData
+	Effects                      []*effectData
+	EnchantedPermanentDiesEffect *effectData
+	EntersGraveyardEffect        *effectData
+	EntersTheBattlefieldEffect   *effectData
+	Flash                        bool
+	Flying                       bool
+	GroundEvader                 bool
+	Hexproof                     bool
+	IsTransformed                bool
+	Kicker                       *effectData
+	Lifelink                     bool
+	Morbid                       *effectData
+	Ninjitsu                     *costData
+	Powermenace                  bool
+	Produces                     []string
+	SacrificesForMana            bool
+	Selector                     *selectorData
+	Token                        bool
+	TransformInto                CardName
+}
+
+type effectData struct {
+	EffectType         string
+	Condition          *conditionData
+	Cost               *costData
+	Damage             int
+	Hexproof           bool
+	Mana               string
+	Plus1Plus1Counters int
+	Power              int
+	Selector           *selectorData
+	Summon             CardName
+	Toughness          int
+	Untargetable       bool
+}
+
+type costData struct {
+	Mana   string
+	Life   int
+	Effect *effectData
+}
+
+// UnmarshalJSON reads a cost written either as its mana, like "1U", or as an object.
+func (cd *costData) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &cd.Mana)
+	}
+	// a plain costData doesn't have this method, so it decodes as a struct
+	type plain costData
+	return decodeStrictly(b, (*plain)(cd))
+}
+
+type selectorData struct {
+	AttackStatus string
+	ControlledBy string
+	Count        int
+	Subtype      string
+	Supertype    string
+	Targeted     bool
+	Type         string
+}
+
+type conditionData struct {
+	ControlAnother       CardName
+	ConvertedManaCostLTE string
+}
+
+// decodeStrictly decodes JSON, with unknown fields as errors.
+func decodeStrictly(b []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// LoadCards reads card definitions in the format of cards.json, and checks them.
+func LoadCards(data []byte) (map[CardName]*Card, error) {
+	rawCards := []json.RawMessage{}
+	if err := json.Unmarshal(data, &rawCards); err != nil {
+		return nil, fmt.Errorf("cards: %s", err)
+	}
+
+	cards := map[CardName]*Card{}
+	for i, raw := range rawCards {
+		cd := &cardData{}
+		if err := decodeStrictly(raw, cd); err != nil {
+			// find the name for the error, if there is one
+			json.Unmarshal(raw, &struct{ Name *CardName }{&cd.Name})
+			if cd.Name == NoCard {
+				return nil, fmt.Errorf("card %d: %s", i, err)
+			}
+			return nil, fmt.Errorf("card %q: %s", cd.Name, err)
+		}
+		if cd.Name == NoCard {
+			return nil, fmt.Errorf("card %d has no Name", i)
+		}
+		if cards[cd.Name] != nil {
+			return nil, fmt.Errorf("card %q is defined twice", cd.Name)
+		}
+		l := &cardLoader{name: cd.Name}
+		card := l.card(cd)
+		if l.err != nil {
+			return nil, l.err
+		}
+		cards[cd.Name] = card
+	}
+
+	// cards can only refer to each other once all of them are loaded
+	for name, card := range cards {
+		l := &cardLoader{name: name, cards: cards}
+		l.checkReferences(card)
+		if l.err != nil {
+			return nil, l.err
+		}
+	}
+	return cards, nil
+}
+
+// A cardLoader turns the data for one card into a Card, keeping the first error.
+type cardLoader struct {
+	name  CardName
+	cards map[CardName]*Card
+	err   error
+}
+
+func (l *cardLoader) fail(format string, args ...interface{}) {
+	if l.err == nil {
+		l.err = fmt.Errorf("card %q: %s", l.name, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l *cardLoader) card(cd *cardData) *Card {
+	if len(cd.Type) == 0 {
+		l.fail("no Type")
+	}
+	card := &Card{
+		ActivatedAbility:             l.effect(cd.ActivatedAbility),
+		AddsTemporaryEffect:          cd.AddsTemporaryEffect,
+		AlternateCastingCost:         l.cost(cd.AlternateCastingCost),
+		BeginningOfEndStepEffect:     l.effect(cd.BeginningOfEndStepEffect),
+		BeginningOfYourUpkeepEffect:  l.effect(cd.BeginningOfYourUpkeepEffect),
+		Bloodthirst:                  cd.Bloodthirst,
+		CastingCost:                  l.cost(cd.CastingCost),
+		DealsCombatDamageEffect:      l.effect(cd.DealsCombatDamageEffect),
+		EntersGraveyardEffect:        l.effect(cd.EntersGraveyardEffect),
+		EntersTheBattlefieldEffect:   l.effect(cd.EntersTheBattlefieldEffect),
+		Flash:                        cd.Flash,
+		Flying:                       cd.Flying,
+		GroundEvader:                 cd.GroundEvader,
+		Hexproof:                     cd.Hexproof,
+		IsTransformed:                cd.IsTransformed,
+		Kicker:                       l.effect(cd.Kicker),
+		Lifelink:                     cd.Lifelink,
+		Morbid:                       l.effect(cd.Morbid),
+		Name:                         cd.Name,
+		Ninjitsu:                     l.cost(cd.Ninjitsu),
+		Powermenace:                  cd.Powermenace,
+		Selector:                     l.selector(cd.Selector),
+		BasePower:                    cd.BasePower,
+		BaseToughness:                cd.BaseToughness,
+		BaseTrample:                  cd.BaseTrample,
+		TransformInto:                cd.TransformInto,
+		Token:                        cd.Token,
+		Text:                         cd.Text,
+		SacrificesForMana:            cd.SacrificesForMana,
+		EnchantedPermanentDiesEffect: l.effect(cd.EnchantedPermanentDiesEffect),
+	}
+	for _, ed := range cd.Effects {
+		card.Effects = append(card.Effects, l.effect(ed))
+	}
+	for _, name := range cd.Type {
+		card.Type = append(card.Type, l.cardType(name))
+	}
+	for _, name := range cd.Subtype {
+		card.Subtype = append(card.Subtype, l.subtype(name))
+	}
+	for _, name := range cd.Supertype {
+		card.Supertype = append(card.Supertype, l.supertype(name))
+	}
+	for _, name := range cd.Produces {
+		card.Produces = append(card.Produces, l.color(name))
+	}
+	if card.CastingCost != nil {
+		card.PhyrexianCastingCost = card.CastingCost.PhyrexianLifeCost()
+	}
+	return card
+}
+
+func (l *cardLoader) effect(ed *effectData) *Effect {
+	if ed == nil {
+		return nil
+	}
+	e := &Effect{
+		Cost:               l.cost(ed.Cost),
+		Damage:             ed.Damage,
+		Hexproof:           ed.Hexproof,
+		Mana:               l.mana(ed.Mana),
+		Plus1Plus1Counters: ed.Plus1Plus1Counters,
+		Power:              ed.Power,
+		Selector:           l.selector(ed.Selector),
+		Summon:             ed.Summon,
+		Toughness:          ed.Toughness,
+		Untargetable:       ed.Untargetable,
+	}
+	if ed.EffectType != "" {
+		e.EffectType = l.effectType(ed.EffectType)
+	}
+	if ed.Condition != nil {
+		e.Condition = &Condition{ControlAnother: ed.Condition.ControlAnother}
+		if ed.Condition.ConvertedManaCostLTE != "" {
+			e.Condition.ConvertedManaCostLTE = l.subtype(ed.Condition.ConvertedManaCostLTE)
+		}
+	}
+	return e
+}
+
+func (l *cardLoader) cost(cd *costData) *Cost {
+	if cd == nil {
+		return nil
+	}
+	cost, err := ParseCost(cd.Mana)
+	if err != nil {
+		l.fail("%s", err)
+		return nil
+	}
+	cost.Life = cd.Life
+	cost.Effect = l.effect(cd.Effect)
+	return cost
+}
+
+// mana reads an amount of mana like "RG", which has no generic, hybrid or
+// Phyrexian symbols.
+func (l *cardLoader) mana(s string) Mana {
+	cost, err := ParseCost(s)
+	if err != nil {
+		l.fail("%s", err)
+		return Mana{}
+	}
+	if cost.Generic > 0 || len(cost.Hybrid) > 0 || len(cost.Phyrexian) > 0 {
+		l.fail("Mana %q can only have the symbols WUBRGC", s)
+	}
+	return cost.Mana
+}
+
+func (l *cardLoader) selector(sd *selectorData) *Selector {
+	if sd == nil {
+		return nil
+	}
+	s := &Selector{Count: sd.Count, Targeted: sd.Targeted}
+	if sd.AttackStatus != "" {
+		s.AttackStatus = l.attackStatus(sd.AttackStatus)
+	}
+	if sd.ControlledBy != "" {
+		s.ControlledBy = l.playerSelector(sd.ControlledBy)
+	}
+	if sd.Subtype != "" {
+		s.Subtype = l.subtype(sd.Subtype)
+	}
+	if sd.Supertype != "" {
+		s.Supertype = l.supertype(sd.Supertype)
+	}
+	if sd.Type != "" {
+		s.Type = l.cardType(sd.Type)
+	}
+	return s
+}
+
+func (l *cardLoader) attackStatus(name string) AttackStatus {
+	return AttackStatus(l.enum("AttackStatus", name, len(_AttackStatus_index)-1, func(i int) string { return AttackStatus(i).String() }))
+}
+
+func (l *cardLoader) cardType(name string) Type {
+	return Type(l.enum("Type", name, len(_Type_index)-1, func(i int) string { return Type(i).String() }))
+}
+
+func (l *cardLoader) color(name string) Color {
+	return Color(l.enum("Color", name, numColors, func(i int) string { return Color(i).String() }))
+}
+
+func (l *cardLoader) effectType(name string) EffectType {
+	return EffectType(l.enum("EffectType", name, len(_EffectType_index)-1, func(i int) string { return EffectType(i).String() }))
+}
+
+func (l *cardLoader) playerSelector(name string) PlayerSelector {
+	return PlayerSelector(l.enum("PlayerSelector", name, len(_PlayerSelector_index)-1, func(i int) string { return PlayerSelector(i).String() }))
+}
+
+func (l *cardLoader) subtype(name string) Subtype {
+	return Subtype(l.enum("Subtype", name, len(_Subtype_index)-1, func(i int) string { return Subtype(i).String() }))
+}
+
+func (l *cardLoader) supertype(name string) Supertype {
+	return Supertype(l.enum("Supertype", name, len(_Supertype_index)-1, func(i int) string { return Supertype(i).String() }))
+}
+
+// enum returns the value of the enum of the given kind that is called name.
+// count is how many values the enum has, and nameOf returns their names.
+func (l *cardLoader) enum(kind string, name string, count int, nameOf func(int) string) int {
+	for i := 0; i < count; i++ {
+		if nameOf(i) == name {
+			return i
+		}
+	}
+	l.fail("unknown %s %q", kind, name)
+	return 0
+}
+
+// checkReferences checks that the cards a card refers to exist.
+func (l *cardLoader) checkReferences(card *Card) {
+	l.reference("TransformInto", card.TransformInto)
+	effects := append([]*Effect{
+		card.ActivatedAbility,
+		card.BeginningOfEndStepEffect,
+		card.BeginningOfYourUpkeepEffect,
+		card.DealsCombatDamageEffect,
+		card.EnchantedPermanentDiesEffect,
+		card.EntersGraveyardEffect,
+		card.EntersTheBattlefieldEffect,
+		card.Kicker,
+		card.Morbid,
+	}, card.Effects...)
+	for _, e := range effects {
+		if e == nil {
+			continue
+		}
+		l.reference("Summon", e.Summon)
+		if e.Condition != nil {
+			l.reference("ControlAnother", e.Condition.ControlAnother)
+		}
+	}
+}
+
+func (l *cardLoader) reference(field string, name CardName) {
+	if name != NoCard && l.cards[name] == nil {
+		l.fail("%s is an unknown card %q", field, name)
+	}
+}
+
+// ParseCost reads a mana cost like "1U", "(R/G)(R/G)" or "1(B/P)".
+// An empty cost or "0" is a cost of nothing.
+func ParseCost(s string) (*Cost, error) {
+	cost := &Cost{}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			j := i
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			n, _ := strconv.Atoi(s[i:j])
+			cost.Generic += n
+			i = j - 1
+		case s[i] == '(':
+			// a hybrid or Phyrexian symbol like (R/G) or (G/P)
+			if i+4 >= len(s) || s[i+2] != '/' || s[i+4] != ')' {
+				return nil, fmt.Errorf("bad mana symbol in cost %q", s)
+			}
+			first, ok := colorForSymbol(s[i+1])
+			if !ok {
+				return nil, fmt.Errorf("bad mana symbol in cost %q", s)
+			}
+			if s[i+3] == 'P' {
+				cost.Phyrexian = append(cost.Phyrexian, first)
+			} else if second, ok := colorForSymbol(s[i+3]); ok {
+				cost.Hybrid = append(cost.Hybrid, []Color{first, second})
+			} else {
+				return nil, fmt.Errorf("bad mana symbol in cost %q", s)
+			}
+			i += 4
+		default:
+			c, ok := colorForSymbol(s[i])
+			if !ok {
+				return nil, fmt.Errorf("bad mana symbol in cost %q", s)
+			}
+			cost.Mana[c]++
+		}
+	}
+	return cost, nil
+}
+
+func colorForSymbol(symbol byte) (Color, bool) {
+	for _, c := range AllColors {
+		if c.Symbol()[0] == symbol {
+			return c, true
+		}
+	}
+	return White, false
+}
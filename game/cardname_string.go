@@ -1,16 +0,0 @@
-// Code generated by "stringer -type=CardName"; DO NOT EDIT.
-
-package game
-
-import "strconv"
-
-const _CardName_name = "NoCardBurningTreeEmissaryCounterspellDazeDelverOfSecretsEldraziSpawnTokenElephantGuideElephantTokenFaerieMiscreantForestGrizzlyBearsGushHungerOfTheHowlpackInsectileAberrationIslandMutagenicGrowthNestInvaderNettleSentinelNinjaOfTheDeepHoursPonderPreordainQuirionRangerRancorSilhanaLedgewalkerSkarrganPitskulkSnapSpellstutterSpriteVaultSkirgeVinesOfVastwood"
-
-var _CardName_index = [...]uint16{0, 6, 25, 37, 41, 56, 73, 86, 99, 114, 120, 132, 136, 155, 174, 180, 195, 206, 220, 239, 245, 254, 267, 273, 291, 307, 311, 329, 340, 355}
-
-func (i CardName) String() string {
-	if i < 0 || i >= CardName(len(_CardName_index)-1) {
-		return "CardName(" + strconv.FormatInt(int64(i), 10) + ")"
-	}
-	return _CardName_name[_CardName_index[i]:_CardName_index[i+1]]
-}
@@ -0,0 +1,116 @@
+package game
+
+// Clone returns a deep copy of the game, for bots to simulate on without
+// changing the real game.
+// Cards are shared between the copies since they never change.
+func (g *Game) Clone() *Game {
+	clone := *g
+	for i, p := range g.Players {
+		clone.Players[i] = p.clone(&clone)
+	}
+	clone.Permanents = make(map[PermanentId]*Permanent, len(g.Permanents))
+	for id, perm := range g.Permanents {
+		clone.Permanents[id] = perm.clone(&clone)
+	}
+	clone.StackObjects = make(map[StackObjectId]*StackObject, len(g.StackObjects))
+	for id, so := range g.StackObjects {
+		clone.StackObjects[id] = so.clone()
+	}
+	clone.Stack = copyStackObjectIds(g.Stack)
+	clone.ChoiceEffect = g.ChoiceEffect.clone()
+	if g.Rng != nil {
+		rng := *g.Rng
+		clone.Rng = &rng
+	}
+	// bots simulate on clones, which shouldn't add to the log
+	clone.Log = nil
+	return &clone
+}
+
+func (p *Player) clone(g *Game) *Player {
+	clone := *p
+	clone.Board = copyPermanentIds(p.Board)
+	clone.Deck = p.Deck.Copy()
+	clone.Hand = copyCardNames(p.Hand)
+	clone.Exile = copyCardNames(p.Exile)
+	clone.Graveyard = copyCardNames(p.Graveyard)
+	clone.Revealed = copyCardNames(p.Revealed)
+	clone.game = g
+	return &clone
+}
+
+func (p *Permanent) clone(g *Game) *Permanent {
+	clone := *p
+	clone.Auras = copyPermanentIds(p.Auras)
+	clone.DamageOrder = copyPermanentIds(p.DamageOrder)
+	if p.TemporaryEffects != nil {
+		clone.TemporaryEffects = make([]*Effect, len(p.TemporaryEffects))
+		for i, e := range p.TemporaryEffects {
+			clone.TemporaryEffects[i] = e.clone()
+		}
+	}
+	clone.game = g
+	return &clone
+}
+
+func (s *StackObject) clone() *StackObject {
+	clone := *s
+	clone.Cost = s.Cost.clone()
+	clone.Kicker = s.Kicker.clone()
+	clone.Selected = copyPermanentIds(s.Selected)
+	return &clone
+}
+
+// The Condition and Selector of an Effect come from its card, so they are
+// shared like cards are.
+func (e *Effect) clone() *Effect {
+	if e == nil {
+		return nil
+	}
+	clone := *e
+	clone.Cards = copyCardNames(e.Cards)
+	if e.ScryCards != nil {
+		clone.ScryCards = make([][]CardName, len(e.ScryCards))
+		for i, names := range e.ScryCards {
+			clone.ScryCards[i] = copyCardNames(names)
+		}
+	}
+	clone.Cost = e.Cost.clone()
+	clone.Kicker = e.Kicker.clone()
+	clone.Selected = copyPermanentIds(e.Selected)
+	return &clone
+}
+
+// The mana symbols of a Cost never change, so only its Effect is copied.
+func (c *Cost) clone() *Cost {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.Effect = c.Effect.clone()
+	return &clone
+}
+
+// The copy functions keep nil slices nil, so a clone serializes the same way
+// as the original.
+
+func copyCardNames(names []CardName) []CardName {
+	if names == nil {
+		return nil
+	}
+	return append(make([]CardName, 0, len(names)), names...)
+}
+
+func copyPermanentIds(ids []PermanentId) []PermanentId {
+	if ids == nil {
+		return nil
+	}
+	return append(make([]PermanentId, 0, len(ids)), ids...)
+}
+
+func copyStackObjectIds(ids []StackObjectId) []StackObjectId {
+	if ids == nil {
+		return nil
+	}
+	return append(make([]StackObjectId, 0, len(ids)), ids...)
+}
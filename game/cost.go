@@ -1,27 +1,109 @@
 /*
-	A Cost currently accomodates colorless, Life for Phyrexian Spells,
-	and Effects such as Quirion Ranger.
-
-	TODO: expand to colored mana
-
+	A Cost accomodates mana of each color, generic mana, hybrid and Phyrexian
+	mana symbols, Life for Phyrexian Spells, and Effects such as Quirion Ranger.
 */
 
 package game
 
 import (
 	"fmt"
+	"strings"
 )
 
 type Cost struct {
-	Colorless int
-	Effect    *Effect
-	Life      int // for Phyrexian
+	Effect *Effect
+
+	// Generic mana can be paid with any type of mana, like the 1 in 1G.
+	Generic int
+
+	// Each hybrid symbol can be paid with any one of its colors, like R/G.
+	Hybrid [][]Color
+
+	Life int // for Phyrexian
+
+	// Mana holds the colored and colorless symbols, like the G in 1G.
+	Mana Mana
+
+	// Each Phyrexian symbol can be paid with its color, or with 2 life when the
+	// spell is cast with its PhyrexianCastingCost.
+	Phyrexian []Color
+}
+
+// ConvertedManaCost is the total amount of mana in the cost, regardless of color.
+func (cc *Cost) ConvertedManaCost() int {
+	return cc.Generic + cc.Mana.Total() + len(cc.Hybrid) + len(cc.Phyrexian)
+}
+
+// PhyrexianLifeCost returns the cost with each Phyrexian symbol paid with 2 life
+// instead of mana, or nil if the cost has no Phyrexian symbols.
+func (cc *Cost) PhyrexianLifeCost() *Cost {
+	if len(cc.Phyrexian) == 0 {
+		return nil
+	}
+	return &Cost{
+		Effect:  cc.Effect,
+		Generic: cc.Generic,
+		Hybrid:  cc.Hybrid,
+		Life:    cc.Life + 2*len(cc.Phyrexian),
+		Mana:    cc.Mana,
+	}
+}
+
+// Plus returns a cost that pays for both costs, like a spell's casting cost
+// and its kicker. The Effect is cc's, or other's if cc has none.
+func (cc *Cost) Plus(other *Cost) *Cost {
+	effect := cc.Effect
+	if effect == nil {
+		effect = other.Effect
+	}
+	return &Cost{
+		Effect:    effect,
+		Generic:   cc.Generic + other.Generic,
+		Hybrid:    append(append([][]Color{}, cc.Hybrid...), other.Hybrid...),
+		Life:      cc.Life + other.Life,
+		Mana:      cc.Mana.Plus(other.Mana),
+		Phyrexian: append(append([]Color{}, cc.Phyrexian...), other.Phyrexian...),
+	}
+}
+
+// manaSymbols returns each mana symbol of the cost, with the colors that can pay it.
+func (cc *Cost) manaSymbols() []manaSymbol {
+	symbols := []manaSymbol{}
+	for c, amount := range cc.Mana {
+		for i := 0; i < amount; i++ {
+			symbols = append(symbols, manaSymbol{colors: []Color{Color(c)}})
+		}
+	}
+	for _, c := range cc.Phyrexian {
+		symbols = append(symbols, manaSymbol{colors: []Color{c}})
+	}
+	for _, colors := range cc.Hybrid {
+		symbols = append(symbols, manaSymbol{colors: colors})
+	}
+	for i := 0; i < cc.Generic; i++ {
+		symbols = append(symbols, manaSymbol{colors: AllColors})
+	}
+	return symbols
 }
 
 func (cc *Cost) String() string {
+	s := ""
+	if cc.Generic > 0 || (cc.Mana.Total() == 0 && len(cc.Hybrid) == 0 && len(cc.Phyrexian) == 0) {
+		s = fmt.Sprintf("%d", cc.Generic)
+	}
+	s += cc.Mana.String()
+	for _, colors := range cc.Hybrid {
+		symbols := []string{}
+		for _, c := range colors {
+			symbols = append(symbols, c.Symbol())
+		}
+		s += fmt.Sprintf("(%s)", strings.Join(symbols, "/"))
+	}
+	for _, c := range cc.Phyrexian {
+		s += fmt.Sprintf("(%s/P)", c.Symbol())
+	}
 	if cc.Life > 0 {
-		return fmt.Sprintf("%d (%d life)", cc.Colorless, cc.Life)
-	} else {
-		return fmt.Sprintf("%d", cc.Colorless)
+		return fmt.Sprintf("%s (%d life)", s, cc.Life)
 	}
+	return s
 }
@@ -1,12 +1,28 @@
 package game
 
 import (
-	"math/rand"
+	"sort"
 )
 
 type Deck struct {
 	Cards        []CardName
 	FailedToDraw bool
+
+	// KnownTop is how many cards on top of the deck its owner has looked at,
+	// like with Ponder, and so knows the order of.
+	KnownTop int
+	// KnownBottom is how many cards on the bottom of the deck its owner put
+	// there, like with Preordain or a mulligan, and so knows the order of.
+	KnownBottom int
+	// RevealedTop is how many cards on top of the deck its owner revealed,
+	// like with Delver of Secrets, so both players know them.
+	RevealedTop int
+
+	// Sideboard cards can be swapped into Cards between games of a Match.
+	Sideboard []CardName
+
+	// cardsHash is the sum of the hashes of Cards. See hash.go.
+	cardsHash uint64
 }
 
 func NewEmptyDeck() *Deck {
@@ -15,13 +31,13 @@ func NewEmptyDeck() *Deck {
 	}
 }
 
-// Constructs a new deck from the count of each card.
+// Constructs a new deck from the count of each card, sorted by card name.
+// NewGame shuffles it.
 func NewDeck(decklist map[CardName]int) *Deck {
 	deck := NewEmptyDeck()
-	for name, count := range decklist {
-		deck.Add(count, name)
+	for _, name := range sortedCardNames(decklist) {
+		deck.Add(decklist[name], name)
 	}
-	deck.Shuffle()
 	return deck
 }
 
@@ -29,8 +45,9 @@ func Stompy() *Deck {
 	return NewDeck(map[CardName]int{
 		BurningTreeEmissary: 4,
 		ElephantGuide:       4,
-		Forest:              17,
+		Forest:              14,
 		HungerOfTheHowlpack: 4,
+		Mountain:            3,
 		NestInvader:         4,
 		NettleSentinel:      4,
 		// QuirionRanger:       4,
@@ -65,16 +82,103 @@ func (d *Deck) Draw() CardName {
 		return NoCard
 	}
 	answer := d.Cards[0]
+	d.cardsHash -= cardFeature(deckFeature, len(d.Cards)-1, answer)
 	d.Cards = d.Cards[1:]
+	if d.KnownTop > 0 {
+		d.KnownTop--
+	}
+	if d.RevealedTop > 0 {
+		d.RevealedTop--
+	}
+	d.KnownBottom = Min(d.KnownBottom, len(d.Cards))
 	return answer
 }
 
-func (d *Deck) Shuffle() {
+// Peek returns the top n cards of the deck, or the whole deck if it has fewer,
+// without drawing them.
+func (d *Deck) Peek(n int) []CardName {
+	return append([]CardName{}, d.Cards[:Min(n, len(d.Cards))]...)
+}
+
+// RemoveFromTop removes the top n cards of the deck, like Peek, without
+// failing to draw when the deck runs out.
+func (d *Deck) RemoveFromTop(n int) {
+	n = Min(n, len(d.Cards))
+	for i, name := range d.Cards[:n] {
+		d.cardsHash -= cardFeature(deckFeature, len(d.Cards)-1-i, name)
+	}
+	d.Cards = d.Cards[n:]
+	d.KnownTop = Max(0, d.KnownTop-n)
+	d.RevealedTop = Max(0, d.RevealedTop-n)
+	d.KnownBottom = Min(d.KnownBottom, len(d.Cards))
+}
+
+func (d *Deck) Shuffle(rng *Rng) {
+	d.KnownTop = 0
+	d.KnownBottom = 0
+	d.RevealedTop = 0
 	for i := len(d.Cards) - 1; i > 0; i-- {
 		// Swap the ith card with a random one in [0..i]
-		j := rand.Int() % (i + 1)
+		j := rng.Intn(i + 1)
 		d.Cards[i], d.Cards[j] = d.Cards[j], d.Cards[i]
 	}
+	d.rehash()
+}
+
+// WithSideboard adds the count of each card to the sideboard, and returns the deck.
+func (d *Deck) WithSideboard(sideboard map[CardName]int) *Deck {
+	for _, name := range sortedCardNames(sideboard) {
+		for i := 0; i < sideboard[name]; i++ {
+			d.Sideboard = append(d.Sideboard, name)
+		}
+	}
+	return d
+}
+
+// Copy returns a copy of the deck that can be played without changing d.
+func (d *Deck) Copy() *Deck {
+	return &Deck{
+		Cards:        copyCardNames(d.Cards),
+		FailedToDraw: d.FailedToDraw,
+		KnownTop:     d.KnownTop,
+		KnownBottom:  d.KnownBottom,
+		RevealedTop:  d.RevealedTop,
+		Sideboard:    copyCardNames(d.Sideboard),
+		cardsHash:    d.cardsHash,
+	}
+}
+
+// Swap replaces one copy of out in the deck with one copy of in from the
+// sideboard, and puts out in the sideboard.
+// It returns false without changing the deck if either card is missing.
+func (d *Deck) Swap(out CardName, in CardName) bool {
+	outIndex := indexOf(d.Cards, out)
+	inIndex := indexOf(d.Sideboard, in)
+	if outIndex < 0 || inIndex < 0 {
+		return false
+	}
+	d.Cards[outIndex] = in
+	d.Sideboard[inIndex] = out
+	d.rehash()
+	return true
+}
+
+func indexOf(names []CardName, name CardName) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func sortedCardNames(counts map[CardName]int) []CardName {
+	names := []CardName{}
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
 }
 
 // Adds cards to the deck, on bottom
@@ -82,11 +186,29 @@ func (d *Deck) Add(n int, name CardName) {
 	for i := 0; i < n; i++ {
 		d.Cards = append(d.Cards, name)
 	}
+	// every other card moves up
+	d.rehash()
+}
+
+// PutOnBottom puts a card on the bottom of the deck, where its owner knows it is.
+func (d *Deck) PutOnBottom(name CardName) {
+	d.Add(1, name)
+	d.KnownBottom++
+}
+
+// Unknown returns the cards between the ones on top and on the bottom that
+// the deck's owner knows the order of.
+func (d *Deck) Unknown() []CardName {
+	top := Min(d.KnownTop, len(d.Cards))
+	return d.Cards[top:Max(top, len(d.Cards)-d.KnownBottom)]
 }
 
 // Adds cards to the deck, on top
 func (d *Deck) AddToTop(n int, name CardName) {
 	for i := 0; i < n; i++ {
 		d.Cards = append([]CardName{name}, d.Cards...)
+		d.cardsHash += cardFeature(deckFeature, len(d.Cards)-1, name)
 	}
+	// the revealed cards aren't on top anymore
+	d.RevealedTop = 0
 }
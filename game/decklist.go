@@ -0,0 +1,192 @@
+/*
+	Decklists in the text format used by MTGO and Arena exports, one card per line:
+
+		4 Delver of Secrets
+		18 Island
+
+		Sideboard
+		4 Hydroblast
+
+	The sideboard starts after a blank line or a "Sideboard" line. Card names are
+	matched ignoring case, spaces and punctuation, so "Burning-Tree Emissary" and
+	"burning tree emissary" are the same card. A real card that isn't in
+	cards.json is looked up in cardnames.txt, so that the error can say it
+	isn't implemented rather than that it doesn't exist.
+*/
+
+package game
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A DecklistError is a line of a decklist that could not be read.
+type DecklistError struct {
+	Line int
+	Text string
+	Err  string
+}
+
+func (e *DecklistError) Error() string {
+	return fmt.Sprintf("decklist line %d: %s: %q", e.Line, e.Err, e.Text)
+}
+
+// cardNamesByKey maps the nameKey of each card to its CardName.
+var cardNamesByKey = map[string]CardName{}
+
+//go:embed cardnames.txt
+var cardNamesText string
+
+// unimplementedByKey maps the nameKey of each real card that Rogue doesn't
+// implement, from cardnames.txt, to its name.
+var unimplementedByKey = map[string]string{}
+
+func init() {
+	for name := range Cards {
+		cardNamesByKey[nameKey(string(name))] = name
+	}
+	for _, line := range strings.Split(cardNamesText, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			unimplementedByKey[nameKey(line)] = line
+		}
+	}
+}
+
+// nameKey is a card name with only its lowercased letters and digits.
+func nameKey(s string) string {
+	key := []rune{}
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key = append(key, unicode.ToLower(r))
+		}
+	}
+	return string(key)
+}
+
+// ParseCardName returns the card with the given name, or an error that says
+// whether there is no such card, Rogue doesn't implement it, or it can't be
+// put in a deck, like a token.
+func ParseCardName(s string) (CardName, error) {
+	name, ok := cardNamesByKey[nameKey(s)]
+	if !ok {
+		if known, ok := unimplementedByKey[nameKey(s)]; ok {
+			return NoCard, fmt.Errorf("%q is not implemented", known)
+		}
+		return NoCard, fmt.Errorf("unknown card %q", s)
+	}
+	card := name.Card()
+	if card.Token || card.IsTransformed {
+		return NoCard, fmt.Errorf("%q cannot be in a deck", name)
+	}
+	return name, nil
+}
+
+// A decklist line is a count, an optional x, the card name, and optionally an
+// Arena set code and collector number, like "4x Ponder (M12) 73".
+var decklistLine = regexp.MustCompile(`^(\d+)x?\s+(.+?)(\s+\([A-Za-z0-9]+\)(\s+\S+)?)?$`)
+
+// ParseDecklist reads a deck and its sideboard from a text decklist.
+func ParseDecklist(r io.Reader) (*Deck, error) {
+	maindeck := map[CardName]int{}
+	sideboard := map[CardName]int{}
+	counts := maindeck
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		text := strings.TrimSpace(scanner.Text())
+		line := text
+		lineCounts := counts
+		if strings.HasPrefix(line, "SB:") {
+			// some MTGO lists mark each sideboard card, like "SB: 2 Hydroblast"
+			line = strings.TrimSpace(strings.TrimPrefix(line, "SB:"))
+			lineCounts = sideboard
+		}
+		switch {
+		case line == "":
+			if len(maindeck) > 0 {
+				counts = sideboard
+			}
+			continue
+		case strings.HasPrefix(line, "//") || strings.HasPrefix(line, "#"):
+			continue
+		case strings.EqualFold(line, "Deck") || strings.EqualFold(line, "Main") ||
+			strings.EqualFold(line, "Maindeck"):
+			counts = maindeck
+			continue
+		case strings.EqualFold(line, "Sideboard"):
+			counts = sideboard
+			continue
+		}
+
+		match := decklistLine.FindStringSubmatch(line)
+		if match == nil {
+			return nil, &DecklistError{lineNumber, text, "expected a count and a card name"}
+		}
+		count, err := strconv.Atoi(match[1])
+		if err != nil || count <= 0 {
+			return nil, &DecklistError{lineNumber, text, "bad count"}
+		}
+		name, err := ParseCardName(match[2])
+		if err != nil {
+			return nil, &DecklistError{lineNumber, text, err.Error()}
+		}
+		lineCounts[name] += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(maindeck) == 0 {
+		return nil, fmt.Errorf("decklist has no cards")
+	}
+	return NewDeck(maindeck).WithSideboard(sideboard), nil
+}
+
+// ReadDecklist reads a deck from a decklist file.
+func ReadDecklist(path string) (*Deck, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	deck, err := ParseDecklist(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return deck, nil
+}
+
+// Decklist returns the deck as a text decklist, with cards in alphabetical
+// order and the sideboard, if there is one, after a blank line.
+func (d *Deck) Decklist() string {
+	var b strings.Builder
+	writeCounts(&b, d.Cards)
+	if len(d.Sideboard) > 0 {
+		b.WriteString("\nSideboard\n")
+		writeCounts(&b, d.Sideboard)
+	}
+	return b.String()
+}
+
+// WriteDecklist writes the deck as a text decklist.
+func (d *Deck) WriteDecklist(w io.Writer) error {
+	_, err := io.WriteString(w, d.Decklist())
+	return err
+}
+
+func writeCounts(b *strings.Builder, names []CardName) {
+	counts := map[CardName]int{}
+	for _, name := range names {
+		counts[name]++
+	}
+	for _, name := range sortedCardNames(counts) {
+		fmt.Fprintf(b, "%d %s\n", counts[name], name)
+	}
+}
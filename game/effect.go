@@ -27,8 +27,9 @@ type Effect struct {
 	Cost *Cost
 
 	// these properties modify a Permanent the Effect targets, or the Game state
-	Colorless          int
+	Damage             int
 	Hexproof           bool
+	Mana               Mana
 	Plus1Plus1Counters int
 	Power              int
 	Toughness          int
@@ -47,8 +48,7 @@ type Effect struct {
 	Source PermanentId
 
 	SelectedForCost PermanentId
-	SpellTarget     StackObjectId
-	Target          PermanentId
+	Target          Target
 
 	// for effects from targeted spells
 	EffectType EffectType
@@ -64,6 +64,7 @@ type EffectType int
 const (
 	AddMana EffectType = iota
 	Countermagic
+	DealDamage
 	DelverScry
 	DelverScryNoReveal
 	DelverScryReveal
@@ -81,11 +82,10 @@ const (
 )
 
 func UpdatedEffectForStackObject(stackObject *StackObject, effect *Effect) *Effect {
-	newEffect := effect
+	newEffect := *effect
 	newEffect.Kicker = stackObject.Kicker
 	newEffect.Source = stackObject.Source
 	newEffect.Target = stackObject.Target
 	newEffect.Selected = stackObject.Selected
-	newEffect.SpellTarget = stackObject.SpellTarget
-	return newEffect
+	return &newEffect
 }
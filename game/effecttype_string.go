@@ -4,9 +4,9 @@ package game
 
 import "strconv"
 
-const _EffectType_name = "AddManaCountermagicDelverScryDelverScryNoRevealDelverScryRevealDrawCardManaSinkReturnCardsToTopDrawReturnScryCardsDrawReturnToHandScryDrawShuffleDrawSpendManaTapLandTopScryDrawUntap"
+const _EffectType_name = "AddManaCountermagicDealDamageDelverScryDelverScryNoRevealDelverScryRevealDrawCardManaSinkReturnCardsToTopDrawReturnScryCardsDrawReturnToHandScryDrawShuffleDrawSpendManaTapLandTopScryDrawUntap"
 
-var _EffectType_index = [...]uint8{0, 7, 19, 29, 47, 63, 71, 79, 99, 118, 130, 138, 149, 158, 165, 176, 181}
+var _EffectType_index = [...]uint8{0, 7, 19, 29, 39, 57, 73, 81, 89, 109, 128, 140, 148, 159, 168, 175, 186, 191}
 
 func (i EffectType) String() string {
 	if i < 0 || i >= EffectType(len(_EffectType_index)-1) {
@@ -0,0 +1,251 @@
+package game
+
+import (
+	"fmt"
+	"math"
+)
+
+// An Evaluator estimates who is winning a game, so that a search can stop
+// playing out a game before it is over.
+type Evaluator interface {
+	// Evaluate returns the chance that the player with the given id wins the
+	// game, between 0 and 1.
+	Evaluate(g *Game, id PlayerId) float64
+	// String says what the evaluator is, for the configuration of the bots
+	// that use it.
+	String() string
+}
+
+/*
+	A HeuristicEvaluator scores what each player has as a weighted sum, and
+	turns the difference between the two scores into a chance of winning
+	with the logistic function.
+*/
+type HeuristicEvaluator struct {
+	Life      float64
+	Power     float64 // of all creatures
+	Toughness float64 // of all creatures
+	Evasion   float64 // the power of creatures that only some creatures can block
+	Hand      float64 // per card
+	Lands     float64
+}
+
+/*
+	DefaultEvaluator is meant for Stompy against Delver. Its weights are what
+	FitDefaultEvaluator fits with DefaultEvaluatorGames and
+	DefaultEvaluatorSeed, rounded to two places.
+*/
+var DefaultEvaluator = &HeuristicEvaluator{
+	Life:      0.17,
+	Power:     0.32,
+	Toughness: 0.24,
+	Evasion:   0.27,
+	Hand:      0.30,
+	Lands:     0.37,
+}
+
+const (
+	DefaultEvaluatorGames = 3000
+	DefaultEvaluatorSeed  = 1
+)
+
+func (e *HeuristicEvaluator) String() string {
+	return fmt.Sprintf("heuristic(life=%g,power=%g,toughness=%g,evasion=%g,hand=%g,lands=%g)",
+		e.Life, e.Power, e.Toughness, e.Evasion, e.Hand, e.Lands)
+}
+
+func (e *HeuristicEvaluator) Evaluate(g *Game, id PlayerId) float64 {
+	if g.IsOver() {
+		switch g.Winner() {
+		case id:
+			return 1
+		case NoPlayerId:
+			return 0.5
+		default:
+			return 0
+		}
+	}
+	p := g.Player(id)
+	x := e.score(p) - e.score(p.Opponent())
+	return 1 / (1 + math.Exp(-x))
+}
+
+func (e *HeuristicEvaluator) score(p *Player) float64 {
+	weights := e.weights()
+	features := heuristicFeatures(p)
+	score := 0.0
+	for i := range weights {
+		score += weights[i] * features[i]
+	}
+	return score
+}
+
+// weights returns the weights in the order of the fields.
+func (e *HeuristicEvaluator) weights() [numHeuristicFeatures]float64 {
+	return [numHeuristicFeatures]float64{e.Life, e.Power, e.Toughness, e.Evasion, e.Hand, e.Lands}
+}
+
+const numHeuristicFeatures = 6
+
+// heuristicFeatures returns what a HeuristicEvaluator weighs for the player,
+// in the order of its fields.
+func heuristicFeatures(p *Player) [numHeuristicFeatures]float64 {
+	life, power, toughness, evasion, hand, lands := float64(p.Life), 0.0, 0.0, 0.0, float64(len(p.Hand)), 0.0
+	for _, perm := range p.GetBoard() {
+		if perm.IsLand() {
+			lands++
+		}
+		if perm.IsCreature() {
+			power += float64(perm.Power())
+			toughness += float64(perm.Toughness())
+			if perm.Flying || perm.GroundEvader || perm.Powermenace {
+				evasion += float64(perm.Power())
+			}
+		}
+	}
+	return [numHeuristicFeatures]float64{life, power, toughness, evasion, hand, lands}
+}
+
+/*
+	Fitting a HeuristicEvaluator
+
+	The Evaluate of a HeuristicEvaluator is a logistic regression: the chance
+	of winning is the logistic function of the weighted differences between
+	the players' features. So the weights can be fitted to games that were
+	played out, by taking the features at the start of each turn and who won.
+*/
+
+// An EvaluatorSample is the differences between the features of a player
+// and their opponent at the start of a turn, and whether the player won.
+type EvaluatorSample struct {
+	Features [numHeuristicFeatures]float64
+	Won      bool
+}
+
+// RecordEvaluatorSamples plays the game out between the strategies, and
+// returns a sample for each player at the start of each turn. A draw has no
+// samples, since neither player won.
+func RecordEvaluatorSamples(g *Game, strategy0 Strategy, strategy1 Strategy) []EvaluatorSample {
+	differences := [][numHeuristicFeatures]float64{}
+	turn := -1
+	for !g.IsOver() {
+		if g.Turn != turn && g.Phase == Upkeep {
+			turn = g.Turn
+			mine, theirs := heuristicFeatures(g.Players[0]), heuristicFeatures(g.Players[1])
+			difference := [numHeuristicFeatures]float64{}
+			for i := range difference {
+				difference[i] = mine[i] - theirs[i]
+			}
+			differences = append(differences, difference)
+		}
+		strategy := []Strategy{strategy0, strategy1}[g.PriorityIndex()]
+		g.TakeAction(strategy.Action(g))
+	}
+
+	winner := g.Winner()
+	samples := []EvaluatorSample{}
+	if winner == NoPlayerId {
+		return samples
+	}
+	for _, difference := range differences {
+		opposite := [numHeuristicFeatures]float64{}
+		for i := range difference {
+			opposite[i] = -difference[i]
+		}
+		samples = append(samples,
+			EvaluatorSample{Features: difference, Won: winner == g.Players[0].Id},
+			EvaluatorSample{Features: opposite, Won: winner == g.Players[1].Id})
+	}
+	return samples
+}
+
+// FitHeuristicEvaluator fits the weights to the samples by logistic
+// regression, with gradient descent on the log loss. The features are scaled
+// to the same spread first, so one step size suits all of them.
+func FitHeuristicEvaluator(samples []EvaluatorSample, steps int) *HeuristicEvaluator {
+	scale := [numHeuristicFeatures]float64{}
+	for _, s := range samples {
+		for i, f := range s.Features {
+			scale[i] += f * f
+		}
+	}
+	for i := range scale {
+		scale[i] = math.Sqrt(scale[i] / float64(len(samples)))
+		if scale[i] == 0 {
+			scale[i] = 1
+		}
+	}
+
+	w := [numHeuristicFeatures]float64{}
+	for step := 0; step < steps; step++ {
+		gradient := [numHeuristicFeatures]float64{}
+		for _, s := range samples {
+			x := 0.0
+			for i, f := range s.Features {
+				x += w[i] * f / scale[i]
+			}
+			y := 0.0
+			if s.Won {
+				y = 1
+			}
+			residual := 1/(1+math.Exp(-x)) - y
+			for i, f := range s.Features {
+				gradient[i] += residual * f / scale[i]
+			}
+		}
+		for i := range w {
+			w[i] -= gradient[i] / float64(len(samples))
+		}
+	}
+
+	for i := range w {
+		w[i] /= scale[i]
+	}
+	return &HeuristicEvaluator{Life: w[0], Power: w[1], Toughness: w[2], Evasion: w[3], Hand: w[4], Lands: w[5]}
+}
+
+// LogLoss returns the average negative log likelihood of the samples under
+// the evaluator, which is lower for an evaluator that predicts them better.
+func (e *HeuristicEvaluator) LogLoss(samples []EvaluatorSample) float64 {
+	weights := e.weights()
+	loss := 0.0
+	for _, s := range samples {
+		x := 0.0
+		for i, f := range s.Features {
+			x += weights[i] * f
+		}
+		if !s.Won {
+			x = -x
+		}
+		loss += math.Log(1 + math.Exp(-x))
+	}
+	return loss / float64(len(samples))
+}
+
+// FitDefaultEvaluator fits a HeuristicEvaluator to games of Stompy against
+// Delver, seeded from seed.
+func FitDefaultEvaluator(games int, seed int64) *HeuristicEvaluator {
+	return FitHeuristicEvaluator(stompyDelverSamples(games, seed), 500)
+}
+
+// stompyDelverSamples plays games of Stompy against Delver between AttackBots
+// and RandomBots, and records samples from them.
+func stompyDelverSamples(games int, seed int64) []EvaluatorSample {
+	rng := NewRng(seed)
+	samples := []EvaluatorSample{}
+	for i := 0; i < games; i++ {
+		decks := [2]*Deck{Stompy(), MonoBlueDelver()}
+		if i%2 == 1 {
+			decks[0], decks[1] = decks[1], decks[0]
+		}
+		// AttackBots against each other play the closest games, and
+		// RandomBots make positions that AttackBots wouldn't
+		strategies := [2]Strategy{&AttackBot{}, &AttackBot{}}
+		if i%4 >= 2 {
+			strategies[(i/4)%2] = NewRandomBot(rng.Int63())
+		}
+		g := NewGame(decks[0], decks[1], rng.Int63())
+		samples = append(samples, RecordEvaluatorSamples(g, strategies[0], strategies[1])...)
+	}
+	return samples
+}
@@ -0,0 +1,339 @@
+/*
+	An EventLog records what happens in a game: every action taken, and the
+	spells resolving, cards changing zones, damage and life changes that
+	follow from it, each stamped with the turn and phase of the action.
+
+	The log doesn't need every rule to report what it does. Before each action
+	it takes a snapshot of the zones and life, and afterwards it logs the
+	differences. Damage is the exception: it is logged by the code that deals
+	it, since a creature that dies of it has no damage left to compare.
+*/
+
+package game
+
+import (
+	"fmt"
+	"sort"
+)
+
+type EventType int
+
+const (
+	ActionEvent EventType = iota
+	ResolveEvent
+	CounterEvent
+	ZoneChangeEvent
+	DamageEvent
+	LifeEvent
+)
+
+type Zone int
+
+const (
+	// NoZone is where tokens come from and go to.
+	NoZone Zone = iota
+	LibraryZone
+	HandZone
+	BattlefieldZone
+	GraveyardZone
+	ExileZone
+	StackZone
+)
+
+var zoneNames = []string{"nowhere", "library", "hand", "battlefield", "graveyard", "exile", "stack"}
+
+func (z Zone) String() string {
+	return zoneNames[z]
+}
+
+type Event struct {
+	Turn  int
+	Phase Phase
+	Type  EventType
+	// the player who took the action, controls what resolved, or whose zone
+	// a card went to, who or whose permanent was dealt damage, or whose life
+	// changed
+	Player PlayerId
+
+	// for an ActionEvent, the action as its player saw it
+	Action string
+	// the card of a resolved or countered spell, the card changing zones, or
+	// the damaged permanent, which is NoPermanentId for damage to a player
+	Card      CardName
+	Permanent PermanentId
+	From      Zone
+	To        Zone
+	// the damage dealt, or the change in life
+	Amount int
+	// the life after a LifeEvent
+	Life int
+}
+
+func (e *Event) String() string {
+	var what string
+	switch e.Type {
+	case ActionEvent:
+		what = e.Action
+	case ResolveEvent:
+		what = fmt.Sprintf("%s resolves", e.stackObjectName())
+	case CounterEvent:
+		what = fmt.Sprintf("%s is countered", e.stackObjectName())
+	case ZoneChangeEvent:
+		what = fmt.Sprintf("%s goes from %s to %s", e.Card, e.From, e.To)
+	case DamageEvent:
+		if e.Permanent == NoPermanentId {
+			what = fmt.Sprintf("is dealt %d damage", e.Amount)
+		} else {
+			what = fmt.Sprintf("%s is dealt %d damage", e.Card, e.Amount)
+		}
+	case LifeEvent:
+		what = fmt.Sprintf("life %+d to %d", e.Amount, e.Life)
+	}
+	return fmt.Sprintf("turn %d %s, player %d: %s", e.Turn, e.Phase, e.Player, what)
+}
+
+func (e *Event) stackObjectName() string {
+	if e.Card == NoCard {
+		return "an ability"
+	}
+	return string(e.Card)
+}
+
+// An EventLog is kept by a game whose Log is set. LoggedGame makes one.
+type EventLog struct {
+	Events []*Event
+	// Replay records the actions taken, if the log was made by LoggedGame.
+	Replay *Replay
+
+	// the damage dealt during the action being taken
+	damage []*Event
+}
+
+// Since returns the events logged after the first n.
+func (l *EventLog) Since(n int) []*Event {
+	return l.Events[n:]
+}
+
+// A logSnapshot is what the log compares before and after an action.
+type logSnapshot struct {
+	turn   int
+	phase  Phase
+	player PlayerId
+	action string
+
+	life  [2]int
+	cards [2]map[Zone]map[CardName]int
+	// the permanents on the battlefield, and the spells on the stack
+	permanents map[PermanentId]zoneEntry
+	stack      map[StackObjectId]zoneEntry
+	top        StackObjectId
+
+	// the ReplayStep of the action, if the log has a Replay
+	step *ReplayStep
+}
+
+type zoneEntry struct {
+	player PlayerId
+	name   CardName
+	// whether a stack object is a spell, which moves its card to the stack
+	spell bool
+}
+
+// start takes the snapshot from before an action.
+func (l *EventLog) start(g *Game, action *Action) *logSnapshot {
+	s := snapshot(g)
+	s.action = action.ShowTo(g.Priority())
+	if l.Replay != nil {
+		step := l.Replay.step(g, action)
+		s.step = &step
+	}
+	return s
+}
+
+func snapshot(g *Game) *logSnapshot {
+	s := &logSnapshot{
+		turn:       g.Turn,
+		phase:      g.Phase,
+		player:     g.PriorityId,
+		permanents: map[PermanentId]zoneEntry{},
+		stack:      map[StackObjectId]zoneEntry{},
+	}
+	for i, p := range g.Players {
+		s.life[i] = p.Life
+		s.cards[i] = map[Zone]map[CardName]int{
+			LibraryZone:   countCards(p.Deck.Cards),
+			HandZone:      countCards(p.Hand),
+			GraveyardZone: countCards(p.Graveyard),
+			ExileZone:     countCards(p.Exile),
+		}
+		for _, id := range p.Board {
+			perm := g.Permanent(id)
+			s.permanents[id] = zoneEntry{player: p.Id, name: perm.FrontFace()}
+		}
+	}
+	for _, id := range g.Stack {
+		so := g.StackObject(id)
+		entry := zoneEntry{player: so.Player, spell: so.Type == Play && so.Card != nil}
+		if so.Card != nil {
+			entry.name = so.Card.Name
+		}
+		s.stack[id] = entry
+		s.top = id
+	}
+	return s
+}
+
+func countCards(names []CardName) map[CardName]int {
+	counts := map[CardName]int{}
+	for _, name := range names {
+		counts[name]++
+	}
+	return counts
+}
+
+// A zoneMove is a card leaving or entering a zone.
+type zoneMove struct {
+	player    PlayerId
+	zone      Zone
+	name      CardName
+	permanent PermanentId
+}
+
+// record logs the events of an action, given the snapshot from before it.
+func (l *EventLog) record(g *Game, action *Action, before *logSnapshot) {
+	if before.step != nil {
+		l.Replay.Steps = append(l.Replay.Steps, *before.step)
+	}
+	after := snapshot(g)
+	add := func(e *Event) {
+		e.Turn, e.Phase = before.turn, before.phase
+		l.Events = append(l.Events, e)
+	}
+	add(&Event{Type: ActionEvent, Player: before.player, Action: before.action})
+
+	left, entered := []zoneMove{}, []zoneMove{}
+	for _, id := range sortedStackObjectIds(before.stack) {
+		entry := before.stack[id]
+		if _, ok := after.stack[id]; ok {
+			continue
+		}
+		eventType := CounterEvent
+		if action.Type == PassPriority && id == before.top {
+			eventType = ResolveEvent
+		}
+		add(&Event{Type: eventType, Player: entry.player, Card: entry.name})
+		if entry.spell {
+			left = append(left, zoneMove{entry.player, StackZone, entry.name, NoPermanentId})
+		}
+	}
+	for _, id := range sortedStackObjectIds(after.stack) {
+		if entry := after.stack[id]; entry.spell {
+			if _, ok := before.stack[id]; !ok {
+				entered = append(entered, zoneMove{entry.player, StackZone, entry.name, NoPermanentId})
+			}
+		}
+	}
+	for _, id := range sortedPermanentIds(before.permanents) {
+		if _, ok := after.permanents[id]; !ok {
+			entry := before.permanents[id]
+			left = append(left, zoneMove{entry.player, BattlefieldZone, entry.name, id})
+		}
+	}
+	for _, id := range sortedPermanentIds(after.permanents) {
+		if _, ok := before.permanents[id]; !ok {
+			entry := after.permanents[id]
+			entered = append(entered, zoneMove{entry.player, BattlefieldZone, entry.name, id})
+		}
+	}
+	for i := range g.Players {
+		for _, zone := range []Zone{LibraryZone, HandZone, GraveyardZone, ExileZone} {
+			b, a := before.cards[i][zone], after.cards[i][zone]
+			for _, name := range sortedCardNames(b) {
+				for n := a[name]; n < b[name]; n++ {
+					left = append(left, zoneMove{PlayerId(i), zone, name, NoPermanentId})
+				}
+			}
+			for _, name := range sortedCardNames(a) {
+				for n := b[name]; n < a[name]; n++ {
+					entered = append(entered, zoneMove{PlayerId(i), zone, name, NoPermanentId})
+				}
+			}
+		}
+	}
+	for _, to := range entered {
+		from := zoneMove{zone: NoZone}
+		if i := matchingMove(left, to); i >= 0 {
+			from = left[i]
+			left = append(left[:i], left[i+1:]...)
+		}
+		permanent := to.permanent
+		if permanent == NoPermanentId {
+			permanent = from.permanent
+		}
+		add(&Event{Type: ZoneChangeEvent, Player: to.player, Card: to.name,
+			Permanent: permanent, From: from.zone, To: to.zone})
+	}
+	for _, from := range left {
+		add(&Event{Type: ZoneChangeEvent, Player: from.player, Card: from.name,
+			Permanent: from.permanent, From: from.zone, To: NoZone})
+	}
+
+	for _, e := range l.damage {
+		add(e)
+	}
+	l.damage = nil
+	for i, life := range after.life {
+		if life != before.life[i] {
+			add(&Event{Type: LifeEvent, Player: PlayerId(i), Amount: life - before.life[i], Life: life})
+		}
+	}
+}
+
+// logDamage logs damage dealt to a player, or to a permanent if perm isn't
+// nil, if the game has a Log.
+func (g *Game) logDamage(player PlayerId, perm *Permanent, amount int) {
+	if g.Log == nil || amount <= 0 {
+		return
+	}
+	e := &Event{Type: DamageEvent, Player: player, Amount: amount}
+	if perm != nil {
+		e.Card, e.Permanent = perm.Name, perm.Id
+	}
+	g.Log.damage = append(g.Log.damage, e)
+}
+
+// matchingMove returns the index of the card leaving a zone that entered
+// another one, preferring the same player's zones, or -1 if there is none.
+func matchingMove(left []zoneMove, to zoneMove) int {
+	match := -1
+	for i, from := range left {
+		if from.name != to.name || from.zone == to.zone {
+			continue
+		}
+		if from.player == to.player {
+			return i
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	return match
+}
+
+func sortedPermanentIds(m map[PermanentId]zoneEntry) []PermanentId {
+	ids := []PermanentId{}
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func sortedStackObjectIds(m map[StackObjectId]zoneEntry) []StackObjectId {
+	ids := []StackObjectId{}
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,248 @@
+/*
+	An ExternalBot is a Strategy that runs another program and asks it which
+	action to take, so bots can be written in any language. The program reads
+	lines on its standard input and writes lines on its standard output, each
+	a command and then its argument, like UCI for chess engines.
+
+	When the program starts, the engine sends
+
+		rogue 1
+
+	with the version of the protocol, and the program answers
+
+		ready
+
+	For each action the bot has to take, the engine sends
+
+		state {"Viewer":0,"Turn":3,...,"Actions":[{"action":"pass"},...]}
+
+	with the View of the game for the bot's player as JSON, on one line. The
+	View's Actions are the legal actions. The program answers with
+
+		action 2
+
+	for the action at that index in Actions, or with an action in the
+	notation of FormatAction, like
+
+		action cast Rancor -> #12
+
+	Before answering, the program can write lines starting with "info", which
+	are printed unless the bot is Quiet. When the engine is done with the
+	program, it sends
+
+		quit
+
+	If the program doesn't answer within the Timeout, answers something that
+	isn't a legal action, or exits, the ExternalBot stops it, keeps the error
+	in Err, and lets its Fallback strategy play from then on. A game the
+	Fallback finished doesn't say how good the program is, so tournaments
+	don't count it.
+*/
+
+package game
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion is the version of the protocol ExternalBot speaks.
+const ProtocolVersion = 1
+
+type ExternalBot struct {
+	// Command is the program to run and its arguments.
+	Command []string
+	// Timeout is how long the program can take to start or to answer.
+	Timeout time.Duration
+	// Fallback plays once the program has failed.
+	Fallback Strategy
+	// Quiet stops the bot from printing the program's info lines. Errors are
+	// always printed.
+	Quiet bool
+	// Err is why the program failed, if it did.
+	Err error
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// lines has the lines the program writes, and is closed when it exits.
+	lines chan string
+}
+
+// NewExternalBot returns a bot that runs the command, split at spaces.
+func NewExternalBot(command string) *ExternalBot {
+	return &ExternalBot{
+		Command:  strings.Fields(command),
+		Timeout:  10 * time.Second,
+		Fallback: &AttackBot{},
+	}
+}
+
+func (b *ExternalBot) String() string {
+	return "ExternalBot"
+}
+
+func (b *ExternalBot) Config() string {
+	return strings.Join(b.Command, " ")
+}
+
+func (b *ExternalBot) Action(g *Game) *Action {
+	if b.Err == nil {
+		action, err := b.ask(g)
+		if err == nil {
+			return action
+		}
+		b.fail(err)
+	}
+	return b.Fallback.Action(g)
+}
+
+// ask sends the game to the program and reads its action.
+func (b *ExternalBot) ask(g *Game) (*Action, error) {
+	if b.cmd == nil {
+		if err := b.start(); err != nil {
+			return nil, err
+		}
+	}
+	view := g.ViewFor(g.PriorityId)
+	bytes, err := json.Marshal(view)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.send("state " + string(bytes)); err != nil {
+		return nil, err
+	}
+	reply, err := b.read("action")
+	if err != nil {
+		return nil, err
+	}
+	if i, err := strconv.Atoi(reply); err == nil {
+		if i < 0 || i >= len(view.Actions) {
+			return nil, fmt.Errorf("action %d is not one of the %d actions", i, len(view.Actions))
+		}
+		return g.DecodeAction(view.Actions[i])
+	}
+	return g.ParseAction(reply)
+}
+
+func (b *ExternalBot) start() error {
+	if len(b.Command) == 0 {
+		return fmt.Errorf("there is no command to run")
+	}
+	b.cmd = exec.Command(b.Command[0], b.Command[1:]...)
+	b.cmd.Stderr = os.Stderr
+	var err error
+	if b.stdin, err = b.cmd.StdinPipe(); err != nil {
+		return err
+	}
+	stdout, err := b.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := b.cmd.Start(); err != nil {
+		return err
+	}
+	lines := make(chan string)
+	b.lines = lines
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	if err := b.send(fmt.Sprintf("rogue %d", ProtocolVersion)); err != nil {
+		return err
+	}
+	_, err = b.read("ready")
+	return err
+}
+
+func (b *ExternalBot) send(line string) error {
+	_, err := io.WriteString(b.stdin, line+"\n")
+	return err
+}
+
+// read returns the argument of the next line with the command, printing info
+// lines until then.
+func (b *ExternalBot) read(command string) (string, error) {
+	timeout := time.After(b.Timeout)
+	for {
+		select {
+		case line, ok := <-b.lines:
+			if !ok {
+				return "", fmt.Errorf("the program exited")
+			}
+			words := strings.SplitN(strings.TrimSpace(line), " ", 2)
+			switch {
+			case words[0] == "info":
+				if !b.Quiet {
+					fmt.Println(line)
+				}
+			case words[0] == command && len(words) == 2:
+				return words[1], nil
+			case words[0] == command:
+				return "", nil
+			default:
+				return "", fmt.Errorf("expected %q from the program, got %q", command, line)
+			}
+		case <-timeout:
+			return "", fmt.Errorf("the program didn't answer within %s", b.Timeout)
+		}
+	}
+}
+
+// fail stops the program after an error.
+func (b *ExternalBot) fail(err error) {
+	b.Err = fmt.Errorf("%s: %v", b.Config(), err)
+	fmt.Fprintln(os.Stderr, b.Err)
+	b.stop()
+}
+
+// Close tells the program to quit, and stops it if it doesn't.
+func (b *ExternalBot) Close() error {
+	if b.cmd == nil || b.cmd.Process == nil {
+		return nil
+	}
+	b.send("quit")
+	b.stdin.Close()
+	cmd, lines := b.cmd, b.lines
+	b.cmd = nil
+	exited := make(chan error, 1)
+	go func() {
+		// the program's output has to be read before waiting for it
+		for range lines {
+		}
+		exited <- cmd.Wait()
+	}()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(b.Timeout):
+		cmd.Process.Kill()
+		return fmt.Errorf("%s didn't quit within %s", b.Config(), b.Timeout)
+	}
+}
+
+// stop kills the program.
+func (b *ExternalBot) stop() {
+	if b.cmd == nil || b.cmd.Process == nil {
+		return
+	}
+	cmd, lines := b.cmd, b.lines
+	b.cmd = nil
+	cmd.Process.Kill()
+	go func() {
+		for range lines {
+		}
+		cmd.Wait()
+	}()
+}
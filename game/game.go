@@ -66,6 +66,24 @@ type Game struct {
 
 	// True if the acting player passed priority after putting a spell or ability on the stack.
 	ActorPassedOnStack bool
+
+	// True once attackers or blockers have been declared for the current phase.
+	// After that, both players get priority like in a main phase.
+	DeclarationFinished bool
+
+	// The seed the game was created with. A game can be replayed exactly from
+	// its seed and the actions taken.
+	Seed int64
+	// Rng is the source of all randomness in the game, like shuffling.
+	Rng *Rng
+
+	// Log records what happens in the game, if it is set.
+	Log *EventLog `json:"-"`
+
+	// The sums of the hashes of the permanents and of the stack, which are
+	// kept up to date as they change. See hash.go.
+	permanentsHash uint64
+	stackHash      uint64
 }
 
 //go:generate stringer -type=Phase
@@ -77,24 +95,53 @@ type Phase int
 // rules. Here it is just treated as another Phase.
 
 const (
-	UntapStep Phase = iota
+	Mulligan Phase = iota
+	UntapStep
 	Upkeep
 	Draw
 	Main1
+	BeginningOfCombat
 	DeclareAttackers
 	DeclareBlockers
 	CombatDamage
+	EndOfCombat
 	Main2
+	EndStep
+	Cleanup
 )
 
-func NewGame(deckToPlay *Deck, deckToDraw *Deck) *Game {
+// MaxHandSize is how many cards a player can keep in hand during Cleanup.
+const MaxHandSize = 7
+
+// HasPriorityWindow is whether players get priority in the phase, to cast
+// instants and activate abilities before passing to end it.
+func (p Phase) HasPriorityWindow() bool {
+	switch p {
+	case Upkeep, Draw, Main1, BeginningOfCombat, CombatDamage, EndOfCombat, Main2, EndStep:
+		return true
+	}
+	return false
+}
+
+// NewGame shuffles both decks with an Rng seeded with seed, and starts a game
+// with them.
+func NewGame(deckToPlay *Deck, deckToDraw *Deck, seed int64) *Game {
+	return newGame(deckToPlay, deckToDraw, seed, true)
+}
+
+func newGame(deckToPlay *Deck, deckToDraw *Deck, seed int64, shuffle bool) *Game {
+	rng := NewRng(seed)
+	if shuffle {
+		deckToPlay.Shuffle(rng)
+		deckToDraw.Shuffle(rng)
+	}
 	players := [2]*Player{
 		NewPlayer(deckToPlay, OnThePlay),
 		NewPlayer(deckToDraw, OnTheDraw),
 	}
 	g := &Game{
 		Players:           players,
-		Phase:             Main1,
+		Phase:             Mulligan,
 		Turn:              0,
 		PriorityId:        OnThePlay,
 		NextPermanentId:   PermanentId(1),
@@ -102,10 +149,13 @@ func NewGame(deckToPlay *Deck, deckToDraw *Deck) *Game {
 		Permanents:        make(map[PermanentId]*Permanent),
 		Stack:             []StackObjectId{},
 		StackObjects:      make(map[StackObjectId]*StackObject),
+		Seed:              seed,
+		Rng:               rng,
 	}
 
 	players[0].game = g
 	players[1].game = g
+	g.rehash()
 
 	return g
 }
@@ -146,14 +196,22 @@ func (g *Game) Actions(forHuman bool) []*Action {
 
 	currentPlayerIsActing := g.PriorityId == g.AttackerId()
 	switch g.Phase {
+	case Mulligan:
+		return g.Priority().MulliganActions()
 	case UntapStep:
 		return append(actions, g.Priority().PassAction())
 	case Upkeep:
 		fallthrough
 	case Draw:
-		actions = append(actions, g.Priority().PlayActions(false, forHuman)...)
-		actions = append(actions, g.Priority().ActivatedAbilityActions(false, forHuman)...)
-		return addManaAndPassActions(forHuman, g, actions)
+		fallthrough
+	case BeginningOfCombat:
+		fallthrough
+	case CombatDamage:
+		fallthrough
+	case EndOfCombat:
+		fallthrough
+	case EndStep:
+		return g.instantSpeedActions(forHuman, actions)
 	case Main1:
 		fallthrough
 	case Main2:
@@ -161,18 +219,40 @@ func (g *Game) Actions(forHuman bool) []*Action {
 		actions = append(actions, g.Priority().ActivatedAbilityActions(currentPlayerIsActing, forHuman)...)
 		return addManaAndPassActions(forHuman, g, actions)
 	case DeclareAttackers:
-		return append(g.Priority().AttackActions(), g.Priority().PassAction())
+		if !g.DeclarationFinished {
+			return append(g.Priority().AttackActions(), &Action{Type: FinishDeclaration})
+		}
+		return g.instantSpeedActions(forHuman, actions)
 	case DeclareBlockers:
-		return append(g.Priority().BlockActions(), g.Priority().PassAction())
-	case CombatDamage:
-		actions = append(actions, g.Priority().PlayActions(false, forHuman)...)
-		actions = append(actions, g.Priority().ActivatedAbilityActions(false, forHuman)...)
-		return addManaAndPassActions(forHuman, g, actions)
+		if !g.DeclarationFinished {
+			return append(g.Priority().BlockActions(), &Action{Type: FinishDeclaration})
+		}
+		return g.instantSpeedActions(forHuman, actions)
+	case Cleanup:
+		discardActions := g.Priority().DiscardActions()
+		if len(discardActions) > 0 {
+			return discardActions
+		}
+		return append(actions, g.Priority().PassAction())
 	default:
 		panic("unhandled phase")
 	}
 }
 
+// instantSpeedActions returns the actions for a phase where the player with
+// priority can only cast instants and activate abilities.
+func (g *Game) instantSpeedActions(forHuman bool, actions []*Action) []*Action {
+	actions = append(actions, g.Priority().PlayActions(false, forHuman)...)
+	actions = append(actions, g.Priority().ActivatedAbilityActions(false, forHuman)...)
+	return addManaAndPassActions(forHuman, g, actions)
+}
+
+// ninjitsuAllowed is whether ninjitsu can be used to swap in an unblocked
+// attacker, which is any time after blockers are declared.
+func (g *Game) ninjitsuAllowed() bool {
+	return (g.Phase == DeclareBlockers && g.DeclarationFinished) || g.Phase == CombatDamage
+}
+
 func addManaAndPassActions(forHuman bool, g *Game, actions []*Action) []*Action {
 	if forHuman && len(actions) <= 1 {
 		actions = appendPassAction(g, actions)
@@ -220,14 +300,19 @@ func (g *Game) HandleCombatDamage() {
 				// Deal damage to blockers
 				for _, blocker := range attacker.GetDamageOrder() {
 					attacker.Damage += blocker.Power()
+					attacker.rehash()
+					g.logDamage(attacker.Owner, attacker, blocker.Power())
 					if damage == 0 {
 						continue
 					}
 					remaining := blocker.Toughness() - blocker.Damage
 					if remaining > damage {
 						blocker.Damage += damage
+						blocker.rehash()
+						g.logDamage(blocker.Owner, blocker, damage)
 						damage = 0
 					} else {
+						g.logDamage(blocker.Owner, blocker, remaining)
 						g.Defender().SendToGraveyard(blocker)
 						damage -= remaining
 					}
@@ -271,6 +356,7 @@ func (g *Game) nextPhase() {
 	}
 
 	g.ActorPassedOnStack = false
+	g.DeclarationFinished = false
 	switch g.Phase {
 	case UntapStep:
 		g.Attacker().Untap()
@@ -286,6 +372,8 @@ func (g *Game) nextPhase() {
 		g.Priority().Draw()
 		g.Phase = Main1
 	case Main1:
+		g.Phase = BeginningOfCombat
+	case BeginningOfCombat:
 		g.Phase = DeclareAttackers
 	case DeclareAttackers:
 		g.Phase = DeclareBlockers
@@ -295,10 +383,26 @@ func (g *Game) nextPhase() {
 		g.PriorityId = g.AttackerId()
 	case CombatDamage:
 		g.HandleCombatDamage()
+		g.Phase = EndOfCombat
+	case EndOfCombat:
 		g.Attacker().EndCombat()
 		g.Defender().EndCombat()
 		g.Phase = Main2
 	case Main2:
+		g.Phase = EndStep
+		for _, c := range g.Attacker().GetBoard() {
+			if c.BeginningOfEndStepEffect != nil {
+				g.AddToStack(&StackObject{
+					Type:   BeginningOfEndStepEffect,
+					Card:   c.Card,
+					Player: g.AttackerId(),
+					Source: c.Id,
+				})
+			}
+		}
+	case EndStep:
+		g.Phase = Cleanup
+	case Cleanup:
 		// End the turn
 		for _, p := range g.Players {
 			p.EndTurn()
@@ -309,15 +413,21 @@ func (g *Game) nextPhase() {
 	}
 }
 
+// TakeAction takes an action that Actions returned, and panics on ones it
+// can't take. Use TryTakeAction for actions that might not be legal.
 func (g *Game) TakeAction(action *Action) {
 	if g.IsOver() {
 		panic("cannot take action when the game is over")
 	}
+	if g.Log != nil {
+		defer g.Log.record(g, action, g.Log.start(g, action))
+	}
 	if action.Type == MakeChoice {
+		// the player making the choice resolves it, like paying for Daze
+		g.Priority().ResolveEffect(action.AfterEffect, nil)
 		if action.ShouldSwitchPriority {
 			g.PriorityId = g.PriorityId.OpponentId()
 		}
-		g.Priority().ResolveEffect(action.AfterEffect, nil)
 		g.ChoiceEffect = nil
 		return
 	}
@@ -330,6 +440,7 @@ func (g *Game) TakeAction(action *Action) {
 			if g.PriorityId == stackObject.Player {
 				g.ActorPassedOnStack = false
 				g.Stack = g.Stack[:len(g.Stack)-1]
+				g.stackHash -= feature(stackObjectFeature, uint64(len(g.Stack)), stackObject.hash())
 				if stackObject.Type == Play {
 					g.Player(stackObject.Player).ResolveSpell(stackObject)
 				} else if stackObject.Type == Activate {
@@ -342,6 +453,9 @@ func (g *Game) TakeAction(action *Action) {
 							break
 						}
 					}
+				} else if stackObject.Type == BeginningOfEndStepEffect {
+					// the effect resolves even if its permanent has left the battlefield
+					g.Player(stackObject.Player).ResolveEffect(stackObject.Card.BeginningOfEndStepEffect, g.Permanents[stackObject.Source])
 				}
 				delete(g.StackObjects, stackObject.Id)
 			}
@@ -354,20 +468,52 @@ func (g *Game) TakeAction(action *Action) {
 		return
 	}
 
+	if action.Type == FinishDeclaration {
+		// the active player gets priority first once declarations are done
+		g.DeclarationFinished = true
+		g.PriorityId = g.AttackerId()
+		return
+	}
+
 	if action.Type == UseForMana {
-		g.Permanent(action.Source).UseForMana()
+		g.Permanent(action.Source).UseForMana(action.Color)
 		return
 	}
 
 	switch g.Phase {
 
+	case Mulligan:
+		if action.Type == KeepHand {
+			g.Priority().KeptHand = true
+		} else if action.Type == TakeMulligan {
+			g.Priority().TakeMulligan()
+		} else if action.Type == PutOnBottom {
+			g.Priority().PutOnBottom(action.Card.Name)
+		} else {
+			panic("expected a mulligan decision during Mulligan")
+		}
+		if g.Priority().FinishedMulligan() {
+			if g.PriorityId == OnThePlay {
+				g.PriorityId = OnTheDraw
+			} else {
+				g.Phase = Main1
+				g.PriorityId = OnThePlay
+			}
+		}
+
 	case Upkeep:
 		fallthrough
 	case Draw:
 		fallthrough
 	case Main1:
 		fallthrough
+	case BeginningOfCombat:
+		fallthrough
+	case EndOfCombat:
+		fallthrough
 	case Main2:
+		fallthrough
+	case EndStep:
 		if action.Type == Play {
 			if action.Card.IsLand() {
 				g.Priority().PlayLand(action)
@@ -381,38 +527,65 @@ func (g *Game) TakeAction(action *Action) {
 		}
 
 	case DeclareAttackers:
+		if g.DeclarationFinished {
+			g.takeInstantSpeedAction(action)
+			return
+		}
 		if action.Type != Attack {
 			panic("expected an attack or a pass during DeclareAttackers")
 		}
 		creature := g.Permanent(action.With)
 		creature.Attacking = true
 		creature.Tapped = true
+		creature.rehash()
 
 	case DeclareBlockers:
+		if g.DeclarationFinished {
+			g.takeInstantSpeedAction(action)
+			return
+		}
 		if action.Type != Block {
 			panic("expected a block or a pass during DeclareBlockers")
 		}
 		creature := g.Permanent(action.With)
-		creature.Blocking = action.Target
-		perm := g.Permanent(action.Target)
+		creature.Blocking = action.Target.Permanent
+		creature.rehash()
+		perm := g.Permanent(action.Target.Permanent)
 		perm.DamageOrder = append(perm.DamageOrder, creature.Id)
+		perm.rehash()
 
 	case CombatDamage:
-		if action.Type == Play {
-			g.Priority().PayCostsAndPutSpellOnStack(action)
-		} else if action.Type == Activate {
-			g.Priority().PayCostsAndPutAbilityOnStack(action)
-		} else {
-			panic("expected a play or activate during CombatDamage")
+		g.takeInstantSpeedAction(action)
+
+	case Cleanup:
+		if action.Type != Discard {
+			panic("expected a discard or a pass during Cleanup")
 		}
+		g.Priority().Discard(action.Card.Name)
 
 	default:
 		panic("unhandled phase")
 	}
 }
 
+// takeInstantSpeedAction takes a play or activate action in a phase where
+// lands and sorcery-speed spells can't be played.
+func (g *Game) takeInstantSpeedAction(action *Action) {
+	if action.Type == Play {
+		g.Priority().PayCostsAndPutSpellOnStack(action)
+	} else if action.Type == Activate {
+		g.Priority().PayCostsAndPutAbilityOnStack(action)
+	} else {
+		panic(fmt.Sprintf("expected a play or activate during %s", g.Phase))
+	}
+}
+
 // Removes targetSpell from the stack, as in when Counterspelled.
+// A countered spell goes to its owner's graveyard.
 func (g *Game) RemoveSpellFromStack(targetSpell StackObjectId) {
+	if so, ok := g.StackObjects[targetSpell]; ok && so.Type == Play && so.Card != nil {
+		g.Player(so.Player).MoveToGraveyard(so.Card.Name)
+	}
 	newStack := []StackObjectId{}
 	for _, spellAction := range g.Stack {
 		if spellAction != targetSpell {
@@ -424,12 +597,27 @@ func (g *Game) RemoveSpellFromStack(targetSpell StackObjectId) {
 	}
 	g.Stack = newStack
 	delete(g.StackObjects, targetSpell)
+	g.rehashStack()
 }
 
 func (g *Game) IsOver() bool {
 	return g.Attacker().Lost() || g.Defender().Lost()
 }
 
+// Winner returns the id of the player who won the game, or NoPlayerId if the
+// game is not over or is a draw.
+func (g *Game) Winner() PlayerId {
+	lost0 := g.Players[0].Lost()
+	lost1 := g.Players[1].Lost()
+	if lost0 && !lost1 {
+		return g.Players[1].Id
+	}
+	if lost1 && !lost0 {
+		return g.Players[0].Id
+	}
+	return NoPlayerId
+}
+
 // All permanents added to the game should be created via newPermanent.
 // This assigns a unique id to the permanent and activates any coming-into-play
 // effects.
@@ -445,7 +633,9 @@ func (g *Game) newPermanent(card *Card, ownerId PlayerId, stackObjectId StackObj
 	if addToBoard {
 		g.Permanents[g.NextPermanentId] = perm
 		owner.Board = append(owner.Board, perm.Id)
+		owner.boardHash += feature(boardFeature, uint64(perm.Id))
 		g.NextPermanentId++
+		perm.rehash()
 		perm.HandleEnterTheBattlefield(stackObjectId)
 	}
 	return perm
@@ -453,6 +643,9 @@ func (g *Game) newPermanent(card *Card, ownerId PlayerId, stackObjectId StackObj
 
 // removePermanent does nothing if the permanent has already been removed.
 func (g *Game) removePermanent(id PermanentId) {
+	if perm, ok := g.Permanents[id]; ok {
+		g.permanentsHash -= perm.hashed
+	}
 	delete(g.Permanents, id)
 }
 
@@ -476,6 +669,7 @@ func (g *Game) AddToStack(stackObject *StackObject) {
 	stackObject.Id = g.NextStackObjectId
 	g.StackObjects[stackObject.Id] = stackObject
 	g.NextStackObjectId++
+	g.stackHash += feature(stackObjectFeature, uint64(len(g.Stack)), stackObject.hash())
 	g.Stack = append(g.Stack, stackObject.Id)
 }
 
@@ -537,8 +731,17 @@ func (g *Game) PriorityIndex() int {
 	panic("game is corrupted")
 }
 
-// Pass makes the active player pass, whichever player has priority
+// Pass makes the active player pass, whichever player has priority.
+// During Cleanup it discards down to the maximum hand size first.
 func (g *Game) pass() {
+	if len(g.Stack) > 0 {
+		g.TakeAction(&Action{Type: PassPriority})
+		return
+	}
+	if g.Phase == Cleanup && len(g.Priority().DiscardActions()) > 0 {
+		g.TakeAction(g.Priority().DiscardActions()[0])
+		return
+	}
 	g.TakeAction(&Action{Type: Pass})
 }
 
@@ -609,7 +812,7 @@ func (g *Game) TakeActionAndResolve(action *Action) {
 // playAura plays the first aura it sees in the hand on its own creature
 func (g *Game) playAura() {
 	for _, a := range g.Priority().PlayActions(true, false) {
-		if a.Card != nil && a.Card.IsEnchantCreature() && g.Permanent(a.Target).Owner == g.PriorityId {
+		if a.Card != nil && a.Card.IsEnchantCreature() && a.Target.Type == TargetPermanent && g.Permanent(a.Target.Permanent).Owner == g.PriorityId {
 			g.TakeActionAndResolve(a)
 			return
 		}
@@ -723,21 +926,6 @@ func DeserializeGame(bytes []byte) *Game {
 	for _, perm := range game.Permanents {
 		perm.game = game
 	}
+	game.rehash()
 	return game
 }
-
-func CopyGame(g *Game) *Game {
-	return DeserializeGame(g.Serialize())
-}
-
-func (g *Game) ActionStates() []*ActionState {
-	actionStates := []*ActionState{}
-	for _, action := range g.Actions(false) {
-		actionState := &ActionState{
-			Game:   g,
-			Action: action,
-		}
-		actionStates = append(actionStates, actionState)
-	}
-	return actionStates
-}
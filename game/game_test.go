@@ -1,9 +1,42 @@
 package game
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
+// newTestGame makes a new game where both players keep their opening hands.
+// The decks are not shuffled, so tests can stack them.
+func newTestGame(deckToPlay *Deck, deckToDraw *Deck) *Game {
+	g := newGame(deckToPlay, deckToDraw, 0, false)
+	g.TakeAction(&Action{Type: KeepHand})
+	g.TakeAction(&Action{Type: KeepHand})
+	return g
+}
+
+// newMidGame plays a game with a RandomBot for at least the given number of
+// actions, and then until the player with priority has a choice to make, so
+// bots can be tested on a position from the middle of a game.
+func newMidGame(deckToPlay *Deck, deckToDraw *Deck, seed int64, actions int) *Game {
+	g := NewGame(deckToPlay, deckToDraw, seed)
+	bot := NewRandomBot(seed)
+	for i := 0; i < actions || len(g.Actions(false)) < 2; i++ {
+		g.TakeAction(bot.Action(g))
+	}
+	return g
+}
+
 // A deck stacked with a certain card c on top and all the rest forests
 func deckWithTopAndForests(name CardName) *Deck {
 	deck := NewEmptyDeck()
@@ -12,8 +45,16 @@ func deckWithTopAndForests(name CardName) *Deck {
 	return deck
 }
 
+// A deck stacked with a certain card c on top and all the rest mountains
+func deckWithTopAndMountains(name CardName) *Deck {
+	deck := NewEmptyDeck()
+	deck.Add(1, name)
+	deck.Add(59, Mountain)
+	return deck
+}
+
 func TestDecking(t *testing.T) {
-	g := NewGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
+	g := newTestGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
 
 	// When each player passes the turn 53 times, both decks should be out of cards
 	for i := 0; i < 53; i++ {
@@ -32,7 +73,7 @@ func TestDecking(t *testing.T) {
 }
 
 func TestTwoBearsFighting(t *testing.T) {
-	g := NewGame(
+	g := newTestGame(
 		deckWithTopAndForests(GrizzlyBears),
 		deckWithTopAndForests(GrizzlyBears))
 
@@ -50,8 +91,7 @@ func TestTwoBearsFighting(t *testing.T) {
 	g.playCreature()
 	g.passTurn()
 
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	attackingBear := g.Attacker().GetCreature(GrizzlyBears)
 	if !attackingBear.Attacking {
@@ -64,7 +104,7 @@ func TestTwoBearsFighting(t *testing.T) {
 	g.TakeAction(&Action{
 		Type:   Block,
 		With:   defendingBear.Id,
-		Target: attackingBear.Id,
+		Target: PermanentTarget(attackingBear.Id),
 	})
 	g.passUntilPhase(Main2)
 
@@ -75,21 +115,30 @@ func TestTwoBearsFighting(t *testing.T) {
 	if len(g.Defender().Creatures()) != 0 {
 		t.Fatal("expected defending bear to die")
 	}
+
+	for _, p := range g.Players {
+		if p.TopOfGraveyard() != GrizzlyBears || len(p.Graveyard) != 1 {
+			t.Fatalf("expected a dead bear in the graveyard, got %v", p.Graveyard)
+		}
+	}
 }
 
 func TestVinesOfVastwoodBuff(t *testing.T) {
-	g := NewGame(topNettleVines(), deckWithTopAndForests(GrizzlyBears))
+	g := newTestGame(topNettleVines(), deckWithTopAndForests(GrizzlyBears))
 
 	g.playLand()
 	g.playCreature()
 	g.passTurn()
 	g.playLand()
 	g.passTurn()
+	g.playLand()
+	g.passTurn()
+	g.passTurn()
 
+	// kicked Vines costs 1GG
 	g.playLand()
 	g.playKickedInstant()
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	g.passUntilPhase(Main2)
 
@@ -99,7 +148,7 @@ func TestVinesOfVastwoodBuff(t *testing.T) {
 }
 
 func TestVinesOfVastwoodUntargetable(t *testing.T) {
-	g := NewGame(topNettleVines(), deckWithTopAndForests(GrizzlyBears))
+	g := newTestGame(topNettleVines(), deckWithTopAndForests(GrizzlyBears))
 
 	g.playLand()
 	g.playCreature()
@@ -121,6 +170,39 @@ func TestVinesOfVastwoodUntargetable(t *testing.T) {
 	}
 }
 
+func TestVinesOfVastwoodKickerCost(t *testing.T) {
+	canKick := func(lands ...CardName) bool {
+		deck := NewEmptyDeck()
+		deck.Add(1, NettleSentinel)
+		deck.Add(1, VinesOfVastwood)
+		for _, land := range lands {
+			deck.Add(1, land)
+		}
+		deck.Add(58-len(lands), Mountain)
+		g := newTestGame(deck, deckWithTopAndForests(GrizzlyBears))
+		g.playLand()
+		g.playCreature()
+		g.passTurn()
+		g.passTurn()
+		g.playLand()
+		g.passTurn()
+		g.passTurn()
+		g.playLand()
+		for _, a := range g.Priority().PlayActions(true, false) {
+			if a.Card != nil && a.Card.Name == VinesOfVastwood && a.WithKicker {
+				return true
+			}
+		}
+		return false
+	}
+	if !canKick(Forest, Forest, Mountain) {
+		t.Fatal("expected to kick Vines of Vastwood with Forest, Forest and Mountain")
+	}
+	if canKick(Forest, Mountain, Mountain) {
+		t.Fatal("expected not to kick Vines of Vastwood with Forest, Mountain and Mountain")
+	}
+}
+
 // A deck stacked with a NettleSentinel and two VinesOfVastwood on top and all the rest forests
 func topNettleVines() *Deck {
 	deck := NewEmptyDeck()
@@ -131,7 +213,7 @@ func topNettleVines() *Deck {
 }
 
 func TestSilhanasDontMeet(t *testing.T) {
-	g := NewGame(deckWithTopAndForests(SilhanaLedgewalker), deckWithTopAndForests(SilhanaLedgewalker))
+	g := newTestGame(deckWithTopAndForests(SilhanaLedgewalker), deckWithTopAndForests(SilhanaLedgewalker))
 
 	g.playLand()
 	g.passTurn()
@@ -147,8 +229,7 @@ func TestSilhanasDontMeet(t *testing.T) {
 	g.playCreature()
 	g.passTurn()
 
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	g.passUntilPhase(DeclareBlockers)
 	if len(g.Actions(false)) > 1 {
@@ -157,7 +238,7 @@ func TestSilhanasDontMeet(t *testing.T) {
 }
 
 func TestSilhanaCantBeTargeted(t *testing.T) {
-	g := NewGame(deckWithTopAndForests(SilhanaLedgewalker), deckWithTopAndForests(VinesOfVastwood))
+	g := newTestGame(deckWithTopAndForests(SilhanaLedgewalker), deckWithTopAndForests(VinesOfVastwood))
 
 	g.playLand()
 	g.passTurn()
@@ -181,8 +262,8 @@ func TestSilhanaCantBeTargeted(t *testing.T) {
 func TestSkarrganPitskulkBloodthirst(t *testing.T) {
 	twoSkulksDeck := NewEmptyDeck()
 	twoSkulksDeck.Add(2, SkarrganPitskulk)
-	twoSkulksDeck.Add(58, Forest)
-	g := NewGame(twoSkulksDeck, deckWithTopAndForests(SkarrganPitskulk))
+	twoSkulksDeck.Add(58, Mountain)
+	g := newTestGame(twoSkulksDeck, deckWithTopAndMountains(SkarrganPitskulk))
 
 	g.playLand()
 	g.playCreature()
@@ -191,8 +272,7 @@ func TestSkarrganPitskulkBloodthirst(t *testing.T) {
 	g.playLand()
 	g.passTurn()
 
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	g.passUntilPhase(Main2)
 
@@ -206,8 +286,8 @@ func TestSkarrganPitskulkBloodthirst(t *testing.T) {
 func TestSkarrganPitskulksDontMeet(t *testing.T) {
 	twoSkulksDeck := NewEmptyDeck()
 	twoSkulksDeck.Add(2, SkarrganPitskulk)
-	twoSkulksDeck.Add(58, Forest)
-	g := NewGame(twoSkulksDeck, deckWithTopAndForests(SkarrganPitskulk))
+	twoSkulksDeck.Add(58, Mountain)
+	g := newTestGame(twoSkulksDeck, deckWithTopAndMountains(SkarrganPitskulk))
 
 	g.playLand()
 	g.playCreature()
@@ -217,8 +297,7 @@ func TestSkarrganPitskulksDontMeet(t *testing.T) {
 	g.playCreature()
 	g.passTurn()
 
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	g.passUntilPhase(Main2)
 
@@ -227,8 +306,7 @@ func TestSkarrganPitskulksDontMeet(t *testing.T) {
 
 	g.passTurn()
 
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	if len(g.Actions(false)) > 2 {
 		t.Fatal("expected the small skulk couldnt block the big skulk")
@@ -236,7 +314,7 @@ func TestSkarrganPitskulksDontMeet(t *testing.T) {
 }
 
 func TestVaultSkirgeLoseAndGain(t *testing.T) {
-	g := NewGame(deckWithTopAndForests(VaultSkirge), deckWithTopAndForests(VaultSkirge))
+	g := newTestGame(deckWithTopAndForests(VaultSkirge), deckWithTopAndForests(VaultSkirge))
 	g.playLand()
 	g.playCreaturePhyrexian()
 	if g.Priority().Life != 18 {
@@ -248,8 +326,7 @@ func TestVaultSkirgeLoseAndGain(t *testing.T) {
 	g.playLand()
 	g.passTurn()
 
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	g.passUntilPhase(Main2)
 	if g.Priority().Life != 19 {
@@ -258,7 +335,7 @@ func TestVaultSkirgeLoseAndGain(t *testing.T) {
 }
 
 func TestNestInvader(t *testing.T) {
-	g := NewGame(deckWithTopAndForests(NestInvader), deckWithTopAndForests(NestInvader))
+	g := newTestGame(deckWithTopAndForests(NestInvader), deckWithTopAndForests(NestInvader))
 	g.playLand()
 	g.passTurn()
 
@@ -274,7 +351,7 @@ func TestNestInvader(t *testing.T) {
 
 	g.playManaAbilityAction()
 
-	if g.Priority().ColorlessManaPool != 1 {
+	if g.Priority().ManaPool[Colorless] != 1 {
 		t.Fatal("expected the player to have a colorless floating")
 	}
 
@@ -284,7 +361,7 @@ func TestNestInvader(t *testing.T) {
 }
 
 func TestBurningTreeEmissary(t *testing.T) {
-	g := NewGame(deckWithTopAndForests(BurningTreeEmissary), deckWithTopAndForests(BurningTreeEmissary))
+	g := newTestGame(deckWithTopAndForests(BurningTreeEmissary), deckWithTopAndForests(BurningTreeEmissary))
 	g.playLand()
 	g.passTurn()
 
@@ -294,13 +371,13 @@ func TestBurningTreeEmissary(t *testing.T) {
 	g.playLand()
 	g.playCreature()
 
-	if g.Priority().ColorlessManaPool != 2 {
+	if g.Priority().ManaPool != (Mana{Red: 1, Green: 1}) {
 		t.Fatal("expected the player to have 2 mana from BurningTreeEmissary")
 	}
 }
 
 func TestQuirionRanger(t *testing.T) {
-	g := NewGame(deckWithTopAndForests(QuirionRanger), deckWithTopAndForests(QuirionRanger))
+	g := newTestGame(deckWithTopAndForests(QuirionRanger), deckWithTopAndForests(QuirionRanger))
 	g.playLand()
 	g.playCreature()
 	g.playActivatedAbility()
@@ -325,7 +402,7 @@ func TestElephantGuide(t *testing.T) {
 	skirgeGuide2.Add(1, VaultSkirge)
 	skirgeGuide2.Add(58, Forest)
 
-	g := NewGame(skirgeGuide, skirgeGuide2)
+	g := newTestGame(skirgeGuide, skirgeGuide2)
 
 	g.playLand()
 	g.playCreature()
@@ -349,8 +426,7 @@ func TestElephantGuide(t *testing.T) {
 	g.playAura()
 	g.passTurn()
 
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	g.passUntilPhase(DeclareBlockers)
 	g.doBlockAction()
@@ -376,7 +452,7 @@ func TestRancor(t *testing.T) {
 	skirgeRancor2.Add(1, VaultSkirge)
 	skirgeRancor2.Add(58, Forest)
 
-	g := NewGame(skirgeRancor, skirgeRancor2)
+	g := newTestGame(skirgeRancor, skirgeRancor2)
 
 	g.playLand()
 	g.playCreature()
@@ -392,8 +468,7 @@ func TestRancor(t *testing.T) {
 		t.Fatal("expected the hand size to be 5 before rancor return")
 	}
 
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	g.passUntilPhase(DeclareBlockers)
 	g.doBlockAction()
@@ -403,13 +478,19 @@ func TestRancor(t *testing.T) {
 	if len(g.Priority().Hand) != 6 {
 		t.Fatal("expected the rancor to return to hand")
 	}
+
+	for _, p := range g.Players {
+		if len(p.Graveyard) != 1 || p.Graveyard[0] != VaultSkirge {
+			t.Fatalf("expected only the skirge in the graveyard, got %v", p.Graveyard)
+		}
+	}
 }
 
 func TestFaerieMiscreant(t *testing.T) {
 	twoMiscreants := NewEmptyDeck()
 	twoMiscreants.Add(2, FaerieMiscreant)
 	twoMiscreants.Add(58, Island)
-	g := NewGame(twoMiscreants, deckWithTopAndForests(BurningTreeEmissary))
+	g := newTestGame(twoMiscreants, deckWithTopAndForests(BurningTreeEmissary))
 
 	g.playLand()
 	g.playCreature()
@@ -433,7 +514,7 @@ func TestMutagenicGrowth(t *testing.T) {
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(skirgeGrowth, allForests)
+	g := newTestGame(skirgeGrowth, allForests)
 
 	g.playLand()
 	g.playCreature()
@@ -452,7 +533,7 @@ func TestGush(t *testing.T) {
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(gush, allForests)
+	g := newTestGame(gush, allForests)
 
 	g.playLand()
 	g.passTurn()
@@ -476,7 +557,7 @@ func TestSnap(t *testing.T) {
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(snapSkirge, allForests)
+	g := newTestGame(snapSkirge, allForests)
 
 	g.playLand()
 	g.playCreature()
@@ -508,7 +589,7 @@ func TestCounterspell(t *testing.T) {
 	counter.Add(1, Counterspell)
 	counter.Add(59, Island)
 
-	g := NewGame(counter, skirge)
+	g := newTestGame(counter, skirge)
 
 	g.playLand()
 	g.passTurn()
@@ -529,6 +610,12 @@ func TestCounterspell(t *testing.T) {
 		g.Print()
 		t.Fatal("expected there to be no spells on the stack after Counterspell")
 	}
+	if g.Attacker().TopOfGraveyard() != VaultSkirge {
+		t.Fatal("expected the countered Vault Skirge in its owner's graveyard")
+	}
+	if g.Defender().TopOfGraveyard() != Counterspell {
+		t.Fatal("expected the resolved Counterspell in the graveyard")
+	}
 }
 
 func TestDazeNotPaid(t *testing.T) {
@@ -540,7 +627,7 @@ func TestDazeNotPaid(t *testing.T) {
 	daze.Add(1, Daze)
 	daze.Add(59, Island)
 
-	g := NewGame(daze, skirge)
+	g := newTestGame(daze, skirge)
 
 	g.playLand()
 	g.passTurn()
@@ -554,7 +641,7 @@ func TestDazeNotPaid(t *testing.T) {
 	g.playInstant()
 	g.TakeAction(&Action{
 		Type:        MakeChoice,
-		AfterEffect: &Effect{EffectType: Countermagic, SpellTarget: g.ChoiceEffect.SpellTarget},
+		AfterEffect: &Effect{EffectType: Countermagic, Target: g.ChoiceEffect.Target},
 	})
 	if len(g.Stack) != 0 {
 		t.Fatal("expected there to be no spells on the stack after Daze")
@@ -564,13 +651,13 @@ func TestDazeNotPaid(t *testing.T) {
 func TestDazePaid(t *testing.T) {
 	skulk := NewEmptyDeck()
 	skulk.Add(1, SkarrganPitskulk)
-	skulk.Add(59, Island)
+	skulk.Add(59, Mountain)
 
 	daze := NewEmptyDeck()
 	daze.Add(1, Daze)
 	daze.Add(59, Island)
 
-	g := NewGame(skulk, daze)
+	g := newTestGame(skulk, daze)
 
 	g.playLand()
 	g.passTurn()
@@ -607,12 +694,13 @@ func TestSpellstutterSpriteSucceeds(t *testing.T) {
 	sprite := NewEmptyDeck()
 	sprite.Add(1, SpellstutterSprite)
 	sprite.Add(1, NettleSentinel)
-	sprite.Add(58, Island)
+	sprite.Add(1, Forest)
+	sprite.Add(57, Island)
 
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(sprite, allForests)
+	g := newTestGame(sprite, allForests)
 
 	g.playLand()
 	g.passTurn()
@@ -666,7 +754,7 @@ func TestSpellstutterSpriteFails(t *testing.T) {
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(sprite, allForests)
+	g := newTestGame(sprite, allForests)
 
 	g.playLand()
 	g.passTurn()
@@ -719,7 +807,7 @@ func TestPonder(t *testing.T) {
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(ponder, allForests)
+	g := newTestGame(ponder, allForests)
 	g.playLand()
 
 	g.playSorcery()
@@ -730,6 +818,9 @@ func TestPonder(t *testing.T) {
 	if len(g.Priority().Hand) != 6 {
 		panic("expected 6 cards in hand after Ponder")
 	}
+	if g.Priority().TopOfGraveyard() != Ponder {
+		panic("expected Ponder in the graveyard after it resolved")
+	}
 }
 
 func TestPreordain(t *testing.T) {
@@ -740,7 +831,7 @@ func TestPreordain(t *testing.T) {
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(preordain, allForests)
+	g := newTestGame(preordain, allForests)
 
 	g.playLand()
 
@@ -767,7 +858,7 @@ func TestNinjaOfTheDeepWater(t *testing.T) {
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(ninja, allForests)
+	g := newTestGame(ninja, allForests)
 
 	g.playLand()
 	g.playCreature()
@@ -777,8 +868,7 @@ func TestNinjaOfTheDeepWater(t *testing.T) {
 
 	g.playLand()
 
-	g.TakeAction(&Action{Type: PassPriority})
-	g.TakeAction(&Action{Type: Pass})
+	g.passUntilPhase(DeclareAttackers)
 	g.attackWithEveryone()
 	g.passUntilPhase(CombatDamage)
 
@@ -805,7 +895,7 @@ func TestDelverFlips(t *testing.T) {
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(delver, allForests)
+	g := newTestGame(delver, allForests)
 
 	g.playLand()
 	g.playCreature()
@@ -817,7 +907,45 @@ func TestDelverFlips(t *testing.T) {
 	if g.Attacker().Creatures()[0].Name != InsectileAberration {
 		panic("expected Delver to transform")
 	}
+	if revealed := g.ViewFor(OnTheDraw).Players[0].RevealedLibrary; len(revealed) != 1 || revealed[0] != Ponder {
+		t.Fatalf("expected the opponent to see the revealed Ponder, got %v", revealed)
+	}
+
+	g.Attacker().SendToGraveyard(g.Attacker().Creatures()[0])
+	if len(g.Attacker().Creatures()) != 0 {
+		t.Fatal("expected no creatures after Insectile Aberration died")
+	}
+	if g.Attacker().TopOfGraveyard() != DelverOfSecrets {
+		t.Fatal("expected Insectile Aberration to be Delver of Secrets in the graveyard")
+	}
+}
+
+func TestTokensDoNotGoToGraveyard(t *testing.T) {
+	g := newTestGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
+	p := g.Priority()
+	token := g.newPermanent(ElephantToken.Card(), p.Id, NoStackObjectId, true)
+	p.SendToGraveyard(token)
+	if len(p.Board) != 0 || len(p.Graveyard) != 0 {
+		t.Fatalf("expected the token to cease to exist, got graveyard %v", p.Graveyard)
+	}
+}
 
+func TestExileAndGraveyardOrder(t *testing.T) {
+	g := newTestGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
+	p := g.Priority()
+	p.MoveToGraveyard(Forest)
+	p.MoveToGraveyard(GrizzlyBears)
+	p.MoveToGraveyard(Forest)
+	p.MoveToExile(Rancor)
+	if p.TopOfGraveyard() != Forest || len(p.GetGraveyard()) != 3 {
+		t.Fatalf("unexpected graveyard %v", p.Graveyard)
+	}
+	if !p.RemoveFromGraveyard(GrizzlyBears) || p.RemoveFromGraveyard(Rancor) {
+		t.Fatal("expected to remove only cards that are in the graveyard")
+	}
+	if len(p.Graveyard) != 2 || p.GetExile()[0].Name != Rancor {
+		t.Fatalf("unexpected zones %v %v", p.Graveyard, p.Exile)
+	}
 }
 
 func TestSerializationDuringSpellstutterSpriteFails(t *testing.T) {
@@ -829,7 +957,7 @@ func TestSerializationDuringSpellstutterSpriteFails(t *testing.T) {
 	allForests := NewEmptyDeck()
 	allForests.Add(60, Forest)
 
-	g := NewGame(sprite, allForests)
+	g := newTestGame(sprite, allForests)
 
 	g.playLand()
 	g.passTurn()
@@ -906,20 +1034,20 @@ func TestSerializationDuringSpellstutterSpriteFails(t *testing.T) {
 
 func BenchmarkStompyPlayout(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		game := NewGame(Stompy(), Stompy())
+		game := NewGame(Stompy(), Stompy(), int64(i))
 		PlayGame(game, &RandomBot{}, &RandomBot{}, false)
 	}
 }
 
 func BenchmarkDelverPlayout(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		game := NewGame(MonoBlueDelver(), MonoBlueDelver())
+		game := NewGame(MonoBlueDelver(), MonoBlueDelver(), int64(i))
 		PlayGame(game, &RandomBot{}, &RandomBot{}, false)
 	}
 }
 
 func BenchmarkStompyGameSerialization(b *testing.B) {
-	game := NewGame(Stompy(), Stompy())
+	game := NewGame(Stompy(), Stompy(), 1)
 	PlayGame(game, &RandomBot{}, &RandomBot{}, false)
 	b.ResetTimer()
 
@@ -930,7 +1058,7 @@ func BenchmarkStompyGameSerialization(b *testing.B) {
 }
 
 func BenchmarkDelverGameSerialization(b *testing.B) {
-	game := NewGame(MonoBlueDelver(), MonoBlueDelver())
+	game := NewGame(MonoBlueDelver(), MonoBlueDelver(), 1)
 	PlayGame(game, &RandomBot{}, &RandomBot{}, false)
 	b.ResetTimer()
 
@@ -939,3 +1067,1464 @@ func BenchmarkDelverGameSerialization(b *testing.B) {
 		DeserializeGame([]byte(s))
 	}
 }
+
+func BenchmarkStompyGameClone(b *testing.B) {
+	game := NewGame(Stompy(), Stompy(), 1)
+	PlayGame(game, &RandomBot{}, &RandomBot{}, false)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		game.Clone()
+	}
+}
+
+func BenchmarkDelverGameClone(b *testing.B) {
+	game := NewGame(MonoBlueDelver(), MonoBlueDelver(), 1)
+	PlayGame(game, &RandomBot{}, &RandomBot{}, false)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		game.Clone()
+	}
+}
+
+func TestForestsCantPayForBlueSpells(t *testing.T) {
+	counter := NewEmptyDeck()
+	counter.Add(1, Counterspell)
+	counter.Add(1, GrizzlyBears)
+	counter.Add(58, Forest)
+
+	g := newTestGame(counter, deckWithTopAndForests(GrizzlyBears))
+
+	g.playLand()
+	g.passTurn()
+
+	g.playLand()
+	g.passTurn()
+
+	g.playLand()
+	g.passTurn()
+
+	g.playLand()
+	g.putCreatureOnStackAndPass()
+
+	for _, a := range g.Actions(false) {
+		if a.Card != nil && a.Card.Name == Counterspell {
+			t.Fatal("expected Counterspell to need blue mana")
+		}
+	}
+}
+
+func TestSpendManaTapsLandsOfTheRightColor(t *testing.T) {
+	deck := NewEmptyDeck()
+	deck.Add(1, Island)
+	deck.Add(1, Forest)
+	deck.Add(1, Island)
+	deck.Add(1, Snap)
+	deck.Add(56, Forest)
+
+	g := newTestGame(deck, deckWithTopAndForests(GrizzlyBears))
+	p := g.Priority()
+	for _, name := range []CardName{Island, Forest, Island} {
+		g.newPermanent(name.Card(), p.Id, NoStackObjectId, true)
+	}
+
+	// 1G could be paid with an Island and a Forest, but that leaves no U for Snap
+	if !p.CanPayCost(&Cost{Generic: 1, Mana: Mana{Blue: 1, Green: 1}}) {
+		t.Fatal("expected to be able to pay 1UG")
+	}
+	p.SpendMana(&Cost{Mana: Mana{Green: 1}})
+	if !p.CanPayCost(Snap.Card().CastingCost) {
+		t.Fatal("expected two Islands to pay for Snap after spending G")
+	}
+	p.SpendMana(Snap.Card().CastingCost)
+	for _, land := range p.Lands() {
+		if !land.Tapped {
+			t.Fatal("expected every land to be tapped")
+		}
+	}
+}
+
+func TestManaPoolPaysHybridBeforeLands(t *testing.T) {
+	g := newTestGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
+	p := g.Priority()
+	g.newPermanent(Forest.Card(), p.Id, NoStackObjectId, true)
+	p.AddMana(Mana{Red: 1, Green: 1})
+
+	p.SpendMana(BurningTreeEmissary.Card().CastingCost)
+	if p.ManaPool.Total() != 0 {
+		t.Fatal("expected RG from the pool to pay for Burning-Tree Emissary, left ", p.ManaPool)
+	}
+	if p.Lands()[0].Tapped {
+		t.Fatal("expected the Forest to stay untapped")
+	}
+}
+
+func TestTurnStructure(t *testing.T) {
+	g := newTestGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
+	phases := []Phase{}
+	for g.Turn == 0 {
+		phases = append(phases, g.Phase)
+		g.pass()
+	}
+	expected := []Phase{
+		Main1, BeginningOfCombat, DeclareAttackers, DeclareBlockers, CombatDamage,
+		EndOfCombat, Main2, EndStep, Cleanup,
+	}
+	if len(phases) != len(expected) {
+		t.Fatalf("expected phases %v, got %v", expected, phases)
+	}
+	for i, phase := range phases {
+		if phase != expected[i] {
+			t.Fatalf("expected phases %v, got %v", expected, phases)
+		}
+	}
+}
+
+func TestSpellstutterSpriteAtEndOfTurn(t *testing.T) {
+	sprite := NewEmptyDeck()
+	sprite.Add(1, SpellstutterSprite)
+	sprite.Add(59, Island)
+
+	g := newTestGame(sprite, deckWithTopAndForests(GrizzlyBears))
+
+	g.playLand()
+	g.passTurn()
+
+	g.passTurn()
+
+	g.playLand()
+	g.passTurn()
+
+	g.passUntilPhase(EndStep)
+	g.TakeAction(&Action{Type: PassPriority})
+	for _, a := range g.Actions(false) {
+		if a.Type == Play && a.Card.Name == SpellstutterSprite {
+			g.TakeActionAndResolve(a)
+			break
+		}
+	}
+	if len(g.Defender().Creatures()) != 1 {
+		t.Fatal("expected Spellstutter Sprite to be flashed in during the end step")
+	}
+
+	g.passTurn()
+	g.passUntilPhase(DeclareAttackers)
+	g.attackWithEveryone()
+	g.passUntilPhase(Main2)
+	if g.Defender().Life != 19 {
+		t.Fatal("expected Spellstutter Sprite to attack the turn after it was flashed in")
+	}
+}
+
+func TestCleanupDiscardsToMaxHandSize(t *testing.T) {
+	gush := NewEmptyDeck()
+	gush.Add(1, Gush)
+	gush.Add(59, Island)
+
+	g := newTestGame(gush, deckWithTopAndForests(GrizzlyBears))
+
+	g.playLand()
+	g.passTurn()
+
+	g.passTurn()
+
+	g.playLand()
+	g.playInstant()
+	g.passUntilPhase(Cleanup)
+
+	discarded := 0
+	for {
+		actions := g.Actions(false)
+		if actions[0].Type != Discard {
+			break
+		}
+		g.TakeAction(actions[0])
+		discarded++
+	}
+	if discarded != 2 || len(g.Priority().Hand) != MaxHandSize {
+		t.Fatalf("expected to discard 2 cards down to %d, discarded %d", MaxHandSize, discarded)
+	}
+	if len(g.Priority().Graveyard) != 3 {
+		t.Fatal("expected Gush and 2 discarded cards in the graveyard")
+	}
+}
+
+func TestBeginningOfEndStepEffect(t *testing.T) {
+	g := newTestGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
+	card := &Card{
+		BeginningOfEndStepEffect: &Effect{EffectType: DrawCard},
+		CastingCost:              &Cost{},
+		Type:                     []Type{Enchantment},
+	}
+	g.newPermanent(card, g.Priority().Id, NoStackObjectId, true)
+
+	g.passUntilPhase(EndStep)
+	if len(g.Stack) != 1 || len(g.Attacker().Hand) != 7 {
+		t.Fatal("expected the effect to go on the stack at the beginning of the end step")
+	}
+	g.TakeAction(&Action{Type: PassPriority})
+	if g.PriorityId != g.DefenderId() || len(g.Stack) != 1 {
+		t.Fatal("expected the opponent to get priority to respond to the effect")
+	}
+	g.TakeAction(&Action{Type: PassPriority})
+	if len(g.Stack) != 0 || len(g.Attacker().Hand) != 8 {
+		t.Fatal("expected to draw a card when the effect resolves")
+	}
+	g.passTurn()
+	g.passUntilPhase(EndStep)
+	if len(g.Defender().Hand) != MaxHandSize || len(g.Attacker().Hand) != 8 {
+		t.Fatal("expected the effect to trigger only on its controller's end step")
+	}
+}
+
+func TestCombatTrickAfterBlocks(t *testing.T) {
+	trick := NewEmptyDeck()
+	trick.Add(1, GrizzlyBears)
+	trick.Add(1, MutagenicGrowth)
+	trick.Add(58, Forest)
+
+	g := newTestGame(trick, deckWithTopAndForests(GrizzlyBears))
+
+	g.playLand()
+	g.passTurn()
+
+	g.playLand()
+	g.passTurn()
+
+	g.playLand()
+	g.playCreature()
+	g.passTurn()
+
+	g.playLand()
+	g.playCreature()
+	g.passTurn()
+
+	g.passUntilPhase(DeclareAttackers)
+	g.TakeAction(g.Priority().AttackActions()[0])
+	g.TakeAction(&Action{Type: FinishDeclaration})
+	if g.Phase != DeclareAttackers || g.PriorityId != g.AttackerId() {
+		t.Fatal("expected the attacker to get priority after declaring attackers")
+	}
+	g.passUntilPhase(DeclareBlockers)
+	g.doBlockAction()
+	g.TakeAction(&Action{Type: FinishDeclaration})
+	if g.PriorityId != g.AttackerId() {
+		t.Fatal("expected the attacker to get priority after blockers are declared")
+	}
+
+	for _, a := range g.Actions(false) {
+		if a.Type == Play && a.Card.Name == MutagenicGrowth && a.WithPhyrexian {
+			g.TakeActionAndResolve(a)
+			break
+		}
+	}
+	g.passUntilPhase(Main2)
+
+	if len(g.Attacker().Creatures()) != 1 {
+		t.Fatal("expected the pumped bear to survive combat")
+	}
+	if len(g.Defender().Creatures()) != 0 {
+		t.Fatal("expected the blocking bear to die")
+	}
+	if g.Attacker().TopOfGraveyard() != MutagenicGrowth {
+		t.Fatal("expected Mutagenic Growth to be cast after blocks")
+	}
+}
+
+func TestNinjitsuAfterBlockersDeclared(t *testing.T) {
+	ninja := NewEmptyDeck()
+	ninja.Add(1, NinjaOfTheDeepHours)
+	ninja.Add(1, FaerieMiscreant)
+	ninja.Add(59, Island)
+
+	g := newTestGame(ninja, deckWithTopAndForests(GrizzlyBears))
+
+	g.playLand()
+	g.playCreature()
+	g.passTurn()
+
+	g.passTurn()
+
+	g.playLand()
+	g.passUntilPhase(DeclareAttackers)
+	g.attackWithEveryone()
+	g.passUntilPhase(DeclareBlockers)
+	for _, a := range g.Actions(false) {
+		if a.WithNinjitsu {
+			t.Fatal("expected no ninjitsu before blockers are declared")
+		}
+	}
+	g.TakeAction(&Action{Type: FinishDeclaration})
+
+	for _, a := range g.Actions(false) {
+		if a.WithNinjitsu {
+			g.TakeActionAndResolve(a)
+			break
+		}
+	}
+	if g.Attacker().GetCreature(NinjaOfTheDeepHours) == nil {
+		t.Fatal("expected ninjitsu in the declare blockers step")
+	}
+}
+
+func TestPlayerTargets(t *testing.T) {
+	g := newTestGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
+	p := g.Priority()
+	opponent := p.Opponent()
+
+	// a sorcery where target player draws a card
+	drawSpell := &Card{
+		Name:        "Target Player Draws",
+		CastingCost: &Cost{Mana: Mana{Blue: 1}},
+		Effects:     []*Effect{&Effect{EffectType: DrawCard, Selector: &Selector{Type: AnyPlayer}}},
+		Type:        []Type{Sorcery},
+	}
+	if !p.IsLegalTarget(drawSpell, PlayerTarget(opponent.Id)) {
+		t.Fatal("expected the opponent to be a legal target")
+	}
+	if p.IsLegalTarget(Ponder.Card(), PlayerTarget(opponent.Id)) {
+		t.Fatal("expected Ponder to be unable to target a player")
+	}
+	if p.IsLegalTarget(Counterspell.Card(), StackObjectTarget(StackObjectId(1))) {
+		t.Fatal("expected a spell that is not on the stack to not be a legal target")
+	}
+
+	p.ResolveEffect(&Effect{EffectType: DrawCard, Target: PlayerTarget(opponent.Id)}, nil)
+	if len(opponent.Hand) != 8 || len(p.Hand) != 7 {
+		t.Fatal("expected the targeted player to draw")
+	}
+
+	p.ResolveEffect(&Effect{EffectType: DealDamage, Damage: 3, Target: PlayerTarget(opponent.Id)}, nil)
+	if opponent.Life != 17 {
+		t.Fatal("expected the targeted player to take 3 damage")
+	}
+
+	bear := g.newPermanent(GrizzlyBears.Card(), opponent.Id, NoStackObjectId, true)
+	p.ResolveEffect(&Effect{EffectType: DealDamage, Damage: 3, Target: PermanentTarget(bear.Id)}, nil)
+	if len(opponent.Creatures()) != 0 || opponent.TopOfGraveyard() != GrizzlyBears {
+		t.Fatal("expected the targeted creature to die from the damage")
+	}
+
+	action := &Action{Type: Play, Card: drawSpell, Target: PlayerTarget(opponent.Id)}
+	if action.ShowTo(p) != "U: Target Player Draws on your opponent" {
+		t.Fatalf("unexpected description %q", action.ShowTo(p))
+	}
+}
+
+func TestLondonMulligan(t *testing.T) {
+	g := NewGame(Stompy(), MonoBlueDelver(), 1)
+	if g.Phase != Mulligan || g.PriorityId != OnThePlay {
+		t.Fatal("expected the player on the play to decide on a mulligan first")
+	}
+	actions := g.Actions(false)
+	if len(actions) != 2 || actions[0].Type != KeepHand || actions[1].Type != TakeMulligan {
+		t.Fatal("expected to choose between keeping and taking a mulligan")
+	}
+
+	p := g.Priority()
+	deckSize := len(p.Deck.Cards)
+	g.TakeAction(&Action{Type: TakeMulligan})
+	if len(p.Hand) != OpeningHandSize || len(p.Deck.Cards) != deckSize || p.Mulligans != 1 {
+		t.Fatal("expected a mulligan to draw a new hand of 7")
+	}
+
+	g.TakeAction(&Action{Type: KeepHand})
+	actions = g.Actions(false)
+	if actions[0].Type != PutOnBottom || g.PriorityId != OnThePlay {
+		t.Fatal("expected to put a card on the bottom after keeping")
+	}
+	g.TakeAction(actions[0])
+	if len(p.Hand) != 6 || p.Deck.Cards[len(p.Deck.Cards)-1] != actions[0].Card.Name {
+		t.Fatal("expected the card to go on the bottom of the deck")
+	}
+
+	if g.PriorityId != OnTheDraw {
+		t.Fatal("expected the player on the draw to decide next")
+	}
+	g.TakeAction(&Action{Type: KeepHand})
+	if g.Phase != Main1 || g.PriorityId != OnThePlay || len(g.Priority().Opponent().Hand) != 7 {
+		t.Fatal("expected the game to start once both players kept")
+	}
+}
+
+// drawingBot plays randomly, always chooses to draw, and sideboards in
+// Quirion Rangers for Vines of Vastwood.
+type drawingBot struct {
+	RandomBot
+}
+
+func (b *drawingBot) ChooseToPlay(m *Match, side int) bool {
+	return false
+}
+
+func (b *drawingBot) Sideboard(m *Match, side int) []SideboardSwap {
+	return []SideboardSwap{{Out: VinesOfVastwood, In: QuirionRanger}}
+}
+
+func TestMatch(t *testing.T) {
+	deck := Stompy().WithSideboard(map[CardName]int{QuirionRanger: 4})
+	m := NewMatch(deck, deck, &drawingBot{}, &RandomBot{}, 1)
+	winner := m.Play()
+
+	if !m.IsOver() || len(m.Winners) < m.GamesToWin || len(m.Winners) > m.MaxGames {
+		t.Fatalf("unexpected games played %v", m.Winners)
+	}
+	if winner != -1 && m.Wins(winner) != m.GamesToWin {
+		t.Fatalf("expected the winner to win %d games, got %v", m.GamesToWin, m.Winners)
+	}
+	for i := 1; i < len(m.Winners); i++ {
+		// side 0 always chooses to draw and side 1 always chooses to play
+		if m.Winners[i-1] != -1 && m.OnThePlay[i] != 1 {
+			t.Fatalf("expected the loser of the last game to choose, got %v on the play after %v",
+				m.OnThePlay, m.Winners)
+		}
+	}
+	if len(m.Decks[0].Cards) != 56 || countOf(m.Decks[0].Sideboard, VinesOfVastwood) != Min(4, len(m.Winners)-1) {
+		t.Fatalf("expected one Vines of Vastwood to be sideboarded out per game, got %v", m.Decks[0].Sideboard)
+	}
+	if countOf(m.Decks[1].Sideboard, QuirionRanger) != 4 {
+		t.Fatal("expected a strategy that is not a MatchStrategy to never sideboard")
+	}
+}
+
+func TestMatchChooserAfterADraw(t *testing.T) {
+	// side 0 chose to draw the first game, and it was a draw
+	m := NewMatch(Stompy(), Stompy(), &drawingBot{}, &RandomBot{}, 1)
+	m.Winners = []int{-1}
+	m.OnThePlay = []int{1}
+	m.Choosers = []int{0}
+	m.Seeds = []int64{1}
+	m.PlayGame()
+	if m.Choosers[1] != 0 || m.OnThePlay[1] != 1 {
+		t.Fatalf("expected side 0 to choose again and draw, got choosers %v and %v on the play",
+			m.Choosers, m.OnThePlay)
+	}
+}
+
+// TestDefaultSideboards checks that sideboarding can't bring back a card
+// that a default deck's function in deck.go comments out of the main deck.
+func TestDefaultSideboards(t *testing.T) {
+	source, err := os.ReadFile("deck.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	letters := regexp.MustCompile("[^a-z]")
+	commentedOut := regexp.MustCompile(`//\s*(\w+):\s*\d+,`)
+	for _, name := range DeckNames() {
+		newDeck := decks[name]
+		funcName := runtime.FuncForPC(reflect.ValueOf(newDeck).Pointer()).Name()
+		funcName = funcName[strings.LastIndex(funcName, ".")+1:]
+		body := string(source)
+		start := strings.Index(body, "func "+funcName+"() *Deck {")
+		if start < 0 {
+			t.Fatalf("expected to find %s in deck.go", funcName)
+		}
+		body = body[start:]
+		body = body[:strings.Index(body, "\n}\n")]
+
+		deck := newDeck()
+		for _, match := range commentedOut.FindAllStringSubmatch(body, -1) {
+			for _, card := range deck.Sideboard {
+				if letters.ReplaceAllString(strings.ToLower(string(card)), "") == strings.ToLower(match[1]) {
+					t.Fatalf("expected %s's sideboard not to have %s, which its main deck comments out", name, card)
+				}
+			}
+		}
+	}
+}
+
+func TestSideboardRules(t *testing.T) {
+	deck := Stompy().WithSideboard(map[CardName]int{QuirionRanger: 4, Rancor: 1})
+	m := NewMatch(deck, Stompy(), &RandomBot{}, &RandomBot{}, 1)
+	if m.ApplySwap(0, SideboardSwap{Out: Forest, In: Rancor}) == nil {
+		t.Fatal("expected to be unable to have a fifth Rancor")
+	}
+	if m.ApplySwap(0, SideboardSwap{Out: Forest, In: DelverOfSecrets}) == nil {
+		t.Fatal("expected to be unable to bring in a card that is not in the sideboard")
+	}
+	if m.ApplySwap(0, SideboardSwap{Out: Rancor, In: QuirionRanger}) != nil {
+		t.Fatal("expected to be able to swap a Rancor for a Quirion Ranger")
+	}
+	if countOf(m.Decks[0].Cards, Rancor) != 3 || countOf(m.Decks[0].Sideboard, Rancor) != 2 {
+		t.Fatal("expected the Rancor to move to the sideboard")
+	}
+	if countOf(deck.Cards, Rancor) != 4 || countOf(deck.Sideboard, Rancor) != 1 {
+		t.Fatal("expected sideboarding not to change the deck the match was made with")
+	}
+}
+
+// recordingBot remembers the actions its strategy takes.
+type recordingBot struct {
+	Strategy
+	actions *[]*Action
+}
+
+func (b *recordingBot) Action(g *Game) *Action {
+	action := b.Strategy.Action(g)
+	*b.actions = append(*b.actions, action)
+	return action
+}
+
+func TestSeededGamesAreReproducible(t *testing.T) {
+	actions := []*Action{}
+	g := NewGame(MonoBlueDelver(), Stompy(), 42)
+	PlayGame(g, &recordingBot{&RandomBot{}, &actions}, &recordingBot{&RandomBot{}, &actions}, false)
+
+	again := NewGame(MonoBlueDelver(), Stompy(), 42)
+	PlayGame(again, &RandomBot{}, &RandomBot{}, false)
+	if string(again.Serialize()) != string(g.Serialize()) {
+		t.Fatal("expected games with the same seed and bots to play out the same")
+	}
+
+	replay := NewGame(MonoBlueDelver(), Stompy(), 42)
+	for _, action := range actions {
+		replay.TakeAction(action)
+	}
+	if string(replay.Serialize()) != string(g.Serialize()) {
+		t.Fatal("expected replaying the actions from the seed to reproduce the game")
+	}
+
+	other := NewGame(MonoBlueDelver(), Stompy(), 43)
+	if string(other.Serialize()) == string(NewGame(MonoBlueDelver(), Stompy(), 42).Serialize()) {
+		t.Fatal("expected a different seed to shuffle differently")
+	}
+}
+
+func TestActionsOnlyLookAtTheDeck(t *testing.T) {
+	deck := NewEmptyDeck()
+	deck.Add(1, Ponder)
+	deck.Add(6, Island)
+	deck.Add(1, Mountain)
+	deck.Add(1, Forest)
+	deck.Add(1, GrizzlyBears)
+	deck.Add(50, Island)
+
+	allForests := NewEmptyDeck()
+	allForests.Add(60, Forest)
+
+	g := newTestGame(deck, allForests)
+	g.playLand()
+	g.playSorcery()
+
+	p := g.Priority()
+	deckSize := len(p.Deck.Cards)
+	actions := g.Actions(false)
+	if len(g.Actions(false)) != len(actions) || len(p.Deck.Cards) != deckSize {
+		t.Fatal("expected looking at the choices for Ponder not to change the deck")
+	}
+
+	for _, action := range actions {
+		cards := action.AfterEffect.Cards
+		if action.AfterEffect.EffectType == ReturnCardsToTopDraw && cards[0] == GrizzlyBears && cards[1] == Forest {
+			g.TakeAction(action)
+		}
+	}
+	if p.Hand[len(p.Hand)-1] != GrizzlyBears || p.Deck.Cards[0] != Forest || p.Deck.Cards[1] != Mountain {
+		t.Fatalf("expected to draw the card put on top, got deck %v", p.Deck.Cards[:3])
+	}
+	if len(p.Deck.Cards) != deckSize-1 {
+		t.Fatal("expected Ponder to draw one card")
+	}
+}
+
+func TestDecklistRoundTrip(t *testing.T) {
+	for _, deck := range []*Deck{Stompy(), MonoBlueDelver()} {
+		parsed, err := ParseDecklist(strings.NewReader(deck.Decklist()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Decklist() != deck.Decklist() || len(parsed.Cards) != len(deck.Cards) {
+			t.Fatalf("expected the same deck after writing and parsing it, got\n%s", parsed.Decklist())
+		}
+	}
+
+	deck, err := ReadDecklist("../decks/delver.txt")
+	if err != nil || deck.Decklist() != MonoBlueDelver().Decklist() {
+		t.Fatal("expected decks/delver.txt to be MonoBlueDelver", err)
+	}
+}
+
+func TestParseDecklist(t *testing.T) {
+	decklist := `Deck
+4x Delver of Secrets (ISD) 51
+2 burning-tree emissary
+// a comment
+16 Island
+SB: 1 Rancor
+
+2 Island
+Sideboard
+1 Quirion Ranger
+`
+	deck, err := ParseDecklist(strings.NewReader(decklist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deck.Cards) != 22 || countOf(deck.Cards, BurningTreeEmissary) != 2 {
+		t.Fatalf("unexpected deck %v", deck.Cards)
+	}
+	if len(deck.Sideboard) != 4 || countOf(deck.Sideboard, Island) != 2 || countOf(deck.Sideboard, Rancor) != 1 {
+		t.Fatalf("unexpected sideboard %v", deck.Sideboard)
+	}
+
+	for _, bad := range []string{"4 Lightning Bolt", "4 Insectile Aberration", "Island", "0 Island", ""} {
+		if _, err := ParseDecklist(strings.NewReader(bad)); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+	_, err = ParseDecklist(strings.NewReader("4 Ponder\n4 Lightning Bolt"))
+	if err, ok := err.(*DecklistError); !ok || err.Line != 2 {
+		t.Fatalf("expected an error on line 2, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"Lightning Bolt" is not implemented`) ||
+		!strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected the error to say Lightning Bolt isn't implemented, got %v", err)
+	}
+	_, err = ParseDecklist(strings.NewReader("4 Ponder\n\n2 Lightnig Bolt"))
+	if err == nil || !strings.Contains(err.Error(), `unknown card "Lightnig Bolt"`) ||
+		!strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("expected the error to say the card is unknown, got %v", err)
+	}
+}
+
+func TestLoadCards(t *testing.T) {
+	cards, err := LoadCards([]byte(`[
+		{"Name": "Bear Cub", "Type": ["Creature"], "CastingCost": "1G", "BasePower": 2, "BaseToughness": 2},
+		{"Name": "Bear Summoning", "Type": ["Sorcery"], "CastingCost": {"Mana": "(R/G)(B/P)", "Life": 1},
+			"Effects": [{"Summon": "Bear Cub", "Selector": {"Type": "Creature", "ControlledBy": "OpposingPlayer"}}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost := cards["Bear Summoning"].CastingCost
+	if cost.String() != "(R/G)(B/P) (1 life)" || cards["Bear Summoning"].PhyrexianCastingCost.Life != 3 {
+		t.Fatalf("unexpected cost %s", cost)
+	}
+	if cards["Bear Summoning"].Effects[0].Selector.ControlledBy != OpposingPlayer || cards["Bear Cub"].BasePower != 2 {
+		t.Fatal("expected the fields of the cards to be loaded")
+	}
+
+	for _, bad := range []string{
+		`[{"Name": "Bear Cub", "Type": ["Creature"], "Flyng": true}]`,
+		`[{"Name": "Bear Cub", "Type": ["Creatur"]}]`,
+		`[{"Name": "Bear Cub", "Type": ["Creature"], "CastingCost": "1X"}]`,
+		`[{"Name": "Bear Cub", "Type": ["Creature"], "CastingCost": {"Mana": "1G", "Lif": 2}}]`,
+		`[{"Name": "Bear Cub", "Type": ["Creature"], "TransformInto": "Bear"}]`,
+		`[{"Name": "Bear Cub", "Type": ["Creature"]}, {"Name": "Bear Cub", "Type": ["Creature"]}]`,
+		`[{"Type": ["Creature"]}]`,
+	} {
+		if _, err := LoadCards([]byte(bad)); err == nil {
+			t.Fatalf("expected an error loading %s", bad)
+		}
+	}
+
+	for name, card := range Cards {
+		if card.Name != name || (card.CastingCost == nil && !card.IsLand()) {
+			t.Fatalf("expected %s to be loaded from cards.json", name)
+		}
+	}
+}
+
+func TestDeterminize(t *testing.T) {
+	deck := NewDeck(map[CardName]int{QuirionRanger: 4, Forest: 20, GrizzlyBears: 20, Rancor: 16})
+	g := NewGame(deck, MonoBlueDelver(), 3)
+	g.TakeAction(&Action{Type: KeepHand})
+	g.TakeAction(&Action{Type: KeepHand})
+	p := g.Priority()
+	p.Hand = append(p.Hand, QuirionRanger, Forest)
+	g.playLand()
+	g.playCreature()
+	g.playActivatedAbility()
+	if len(p.Revealed) != 1 || p.Revealed[0] != Forest {
+		t.Fatalf("expected the returned Forest to be revealed, got %v", p.Revealed)
+	}
+
+	viewer := p.Opponent()
+	viewer.Deck.KnownTop = 2
+	bottom := viewer.Deck.Cards[0]
+	viewer.Deck.Cards = viewer.Deck.Cards[1:]
+	viewer.Deck.PutOnBottom(bottom)
+	rng := NewRng(1)
+	for i := 0; i < 20; i++ {
+		d := g.Determinize(viewer.Id, rng)
+		dp := d.Player(p.Id)
+		if len(dp.Hand) != len(p.Hand) || indexOf(dp.Hand, Forest) < 0 {
+			t.Fatalf("expected the revealed Forest to stay in hand, got %v", dp.Hand)
+		}
+		if len(dp.Deck.Cards) != len(p.Deck.Cards) ||
+			countOf(append(dp.Hand, dp.Deck.Cards...), GrizzlyBears) != countOf(append(p.Hand, p.Deck.Cards...), GrizzlyBears) {
+			t.Fatal("expected the hidden cards to be shuffled between hand and library")
+		}
+		dv := d.Player(viewer.Id)
+		if dv.Deck.Cards[0] != viewer.Deck.Cards[0] || dv.Deck.Cards[1] != viewer.Deck.Cards[1] ||
+			countOf(dv.Deck.Cards, Island) != countOf(viewer.Deck.Cards, Island) {
+			t.Fatal("expected the viewer to keep the cards they know on top of their library")
+		}
+		if dv.Deck.Cards[len(dv.Deck.Cards)-1] != bottom {
+			t.Fatal("expected the viewer to keep the card they put on the bottom of their library")
+		}
+		if d.InformationSetHash(viewer.Id) != g.InformationSetHash(viewer.Id) {
+			t.Fatal("expected a determinization to be in the same information set")
+		}
+	}
+
+	before := g.InformationSetHash(viewer.Id)
+	p.Life--
+	if g.InformationSetHash(viewer.Id) == before {
+		t.Fatal("expected a change the viewer can see to change the information set")
+	}
+}
+
+func TestMcstBotDoesNotChangeTheGame(t *testing.T) {
+	g := newMidGame(Stompy(), MonoBlueDelver(), 5, 40)
+	before := string(g.Serialize())
+
+	mcst := NewMcstBot()
+	mcst.CalculationTime = 0.05
+	mcst.Quiet = true
+	action := mcst.Action(g)
+	if string(g.Serialize()) != before {
+		t.Fatal("expected McstBot to only simulate copies of the game")
+	}
+	g.TakeAction(action)
+}
+
+func TestGameHash(t *testing.T) {
+	g := NewGame(Stompy(), MonoBlueDelver(), 7)
+	bot := NewRandomBot(7)
+	for i := 0; i < 60 && !g.IsOver(); i++ {
+		g.TakeAction(bot.Action(g))
+	}
+	if DeserializeGame(g.Serialize()).Hash() != g.Hash() {
+		t.Fatal("expected a copy of the game to have the same hash")
+	}
+
+	rng := NewRng(7)
+	for i := 0; i < 20; i++ {
+		d := g.Determinize(OnThePlay, rng)
+		if d.InformationSetHash(OnThePlay) != g.InformationSetHash(OnThePlay) {
+			t.Fatal("expected a determinization to have the same information set hash")
+		}
+	}
+
+	before := g.Hash()
+	infoSet := g.InformationSetHash(OnThePlay)
+	p := g.Player(OnTheDraw)
+	p.Life--
+	if g.Hash() == before || g.InformationSetHash(OnThePlay) == infoSet {
+		t.Fatal("expected a change in life to change the hashes")
+	}
+	p.Life++
+	if g.Hash() != before {
+		t.Fatal("expected the hash to go back with the state")
+	}
+	// this changes the library without its methods, which keep its hash up to date
+	p.Deck.Cards[0], p.Deck.Cards[len(p.Deck.Cards)-1] = p.Deck.Cards[len(p.Deck.Cards)-1], p.Deck.Cards[0]
+	p.Deck.rehash()
+	if p.Deck.Cards[0] != p.Deck.Cards[len(p.Deck.Cards)-1] && g.Hash() == before {
+		t.Fatal("expected the order of a library to change the hash")
+	}
+	if g.InformationSetHash(OnThePlay) != infoSet {
+		t.Fatal("expected the order of the opponent's library not to change the information set hash")
+	}
+}
+
+func TestGameHashIsKeptUpToDate(t *testing.T) {
+	decks := [][2]func() *Deck{
+		{Stompy, Stompy},
+		{Stompy, MonoBlueDelver},
+		{MonoBlueDelver, Stompy},
+		{MonoBlueDelver, MonoBlueDelver},
+	}
+	for seed := int64(0); seed < 20; seed++ {
+		pair := decks[seed%int64(len(decks))]
+		g := NewGame(pair[0](), pair[1](), seed)
+		bot := NewRandomBot(seed)
+		for !g.IsOver() {
+			g.TakeAction(bot.Action(g))
+			rehashed := g.Clone()
+			rehashed.rehash()
+			if g.Hash() != rehashed.Hash() ||
+				g.InformationSetHash(OnThePlay) != rehashed.InformationSetHash(OnThePlay) ||
+				g.InformationSetHash(OnTheDraw) != rehashed.InformationSetHash(OnTheDraw) {
+				g.Print()
+				t.Fatalf("expected the hash to be kept up to date, seed %d turn %d", seed, g.Turn)
+			}
+		}
+	}
+}
+
+func BenchmarkInformationSetHash(b *testing.B) {
+	game := NewGame(Stompy(), MonoBlueDelver(), 1)
+	bot := NewRandomBot(1)
+	for i := 0; i < 60 && !game.IsOver(); i++ {
+		game.TakeAction(bot.Action(game))
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		game.InformationSetHash(OnThePlay)
+	}
+}
+
+func TestClone(t *testing.T) {
+	g := NewGame(Stompy(), MonoBlueDelver(), 11)
+	bot := NewRandomBot(11)
+	for i := 0; i < 80 && !g.IsOver(); i++ {
+		g.TakeAction(bot.Action(g))
+	}
+	before := string(g.Serialize())
+	clone := g.Clone()
+	if string(clone.Serialize()) != before {
+		t.Fatal("expected a clone to serialize the same as the game")
+	}
+	for _, p := range clone.Players {
+		if p.game != clone {
+			t.Fatal("expected the players of a clone to belong to the clone")
+		}
+	}
+	for _, perm := range clone.Permanents {
+		if perm.game != clone {
+			t.Fatal("expected the permanents of a clone to belong to the clone")
+		}
+	}
+
+	PlayGame(clone, NewRandomBot(1), NewRandomBot(2), false)
+	if string(g.Serialize()) != before {
+		t.Fatal("expected playing out a clone not to change the game")
+	}
+}
+
+func TestSimpleMonteCarloBotDoesNotChangeTheGame(t *testing.T) {
+	g := newMidGame(Stompy(), Stompy(), 13, 30)
+	before := string(g.Serialize())
+	mc := &SimpleMonteCarloBot{Rng: NewRng(13)}
+	for i := range g.Actions(false) {
+		mc.calcWinRate(g, i, 5)
+	}
+	if string(g.Serialize()) != before {
+		t.Fatal("expected SimpleMonteCarloBot to only simulate copies of the game")
+	}
+}
+
+func TestMcstBotWorkersAreReproducible(t *testing.T) {
+	g := newMidGame(Stompy(), MonoBlueDelver(), 17, 40)
+
+	search := func(workers int) (*McstBot, *Action) {
+		mcst := NewMcstBot()
+		mcst.Rng = NewRng(17)
+		mcst.Workers = workers
+		mcst.Playouts = 20
+		mcst.Quiet = true
+		return mcst, mcst.Action(g)
+	}
+	one, action := search(1)
+	_, again := search(1)
+	if action.hash() != again.hash() {
+		t.Fatal("expected the same seed to choose the same action")
+	}
+
+	many, action := search(4)
+	_, again = search(4)
+	if action.hash() != again.hash() {
+		t.Fatal("expected the same seed to choose the same action with workers")
+	}
+	if len(many.trees) != 4 {
+		t.Fatalf("expected a tree for each worker, got %d", len(many.trees))
+	}
+	for _, e := range one.trees[0].root.edges {
+		if many.trees[0].root.edge(e.action).plays != e.plays {
+			t.Fatal("expected the first worker to search like a single worker")
+		}
+	}
+}
+
+func TestMcstBotKeepsItsTree(t *testing.T) {
+	g := newMidGame(Stompy(), MonoBlueDelver(), 19, 40)
+	mcst := NewMcstBot()
+	mcst.Rng = NewRng(19)
+	mcst.Workers = 1
+	mcst.Playouts = 200
+	mcst.MaxNodes = 50
+	mcst.Quiet = true
+	mcst.Action(g)
+
+	tree := mcst.trees[0]
+	if tree.nodes > mcst.MaxNodes || tree.nodes != tree.root.count() {
+		t.Fatalf("expected at most %d nodes, got %d", mcst.MaxNodes, tree.nodes)
+	}
+	var child *mcstNode
+	for _, e := range tree.root.edges {
+		for _, c := range e.sortedChildren() {
+			if child == nil || c.visits > child.visits {
+				child = c
+			}
+		}
+	}
+	if child == nil {
+		t.Fatal("expected the search to add nodes below the root")
+	}
+	visits := child.visits
+	tree.moveRoot(child.infoSet)
+	if tree.root != child || tree.root.visits != visits || tree.nodes != child.count() {
+		t.Fatal("expected the subtree for the new information set to become the tree")
+	}
+	tree.moveRoot(0)
+	if tree.root.visits != 0 || tree.nodes != 1 {
+		t.Fatal("expected a new tree for an information set that wasn't searched")
+	}
+}
+
+func TestMcstBotWithTinyMaxNodes(t *testing.T) {
+	g := newMidGame(Stompy(), MonoBlueDelver(), 19, 40)
+	for _, maxNodes := range []int{1, 3} {
+		mcst := NewMcstBot()
+		mcst.Rng = NewRng(19)
+		mcst.Workers = 4
+		mcst.Playouts = 50
+		mcst.MaxNodes = maxNodes
+		mcst.Quiet = true
+		mcst.Action(g)
+		for _, tree := range mcst.trees {
+			if tree.nodes > 2 || tree.nodes != tree.root.count() {
+				t.Fatalf("expected at most 2 nodes per tree with MaxNodes %d, got %d", maxNodes, tree.nodes)
+			}
+		}
+	}
+}
+
+func TestHeuristicEvaluator(t *testing.T) {
+	g := NewGame(Stompy(), MonoBlueDelver(), 23)
+	bot := NewRandomBot(23)
+	for i := 0; i < 60 && !g.IsOver(); i++ {
+		g.TakeAction(bot.Action(g))
+	}
+	e := DefaultEvaluator
+	chance := e.Evaluate(g, OnThePlay)
+	if math.Abs(chance+e.Evaluate(g, OnTheDraw)-1) > 1e-9 {
+		t.Fatal("expected the chances of the two players to add up to 1")
+	}
+	g.Player(OnThePlay).Life += 5
+	if e.Evaluate(g, OnThePlay) <= chance {
+		t.Fatal("expected more life to be better")
+	}
+	g.Player(OnTheDraw).Life = 0
+	if e.Evaluate(g, OnThePlay) != 1 || e.Evaluate(g, OnTheDraw) != 0 {
+		t.Fatal("expected a game that is over to be decided")
+	}
+}
+
+func TestFitHeuristicEvaluator(t *testing.T) {
+	samples := stompyDelverSamples(40, 37)
+	if len(samples) == 0 {
+		t.Fatal("expected the games to have samples")
+	}
+	fitted := FitHeuristicEvaluator(samples, 200)
+	if fitted.LogLoss(samples) >= (&HeuristicEvaluator{}).LogLoss(samples) {
+		t.Fatal("expected the fitted weights to predict the games better than no weights")
+	}
+	if fitted.Life <= 0 {
+		t.Fatalf("expected life to be worth something, got %s", fitted)
+	}
+}
+
+func TestMcstBotCutsOffPlayouts(t *testing.T) {
+	g := newMidGame(Stompy(), MonoBlueDelver(), 29, 40)
+	mcst := NewMcstBot()
+	mcst.Rng = NewRng(29)
+	mcst.Workers = 1
+	mcst.Playouts = 30
+	mcst.PlayoutDepth = 5
+	mcst.Quiet = true
+	g.TakeAction(mcst.Action(g))
+
+	for _, e := range mcst.trees[0].root.edges {
+		if e.wins != math.Trunc(e.wins) {
+			return
+		}
+	}
+	t.Fatal("expected cut off playouts to count as part of a win")
+}
+
+func TestRolloutPolicies(t *testing.T) {
+	policies := []*RolloutPolicy{RandomRollout, AttackRollout, GreedyRollout,
+		EpsilonGreedyRollout(0.2, AttackRollout)}
+	for i, policy := range policies {
+		g := newMidGame(Stompy(), MonoBlueDelver(), 31, 40)
+		mcst := NewMcstBot()
+		mcst.Rng = NewRng(31)
+		mcst.Workers = 2
+		mcst.Playouts = 5
+		mcst.Rollout = policy
+		mcst.Quiet = true
+		g.TakeAction(mcst.Action(g))
+		if mcst.trees[0].rollout == nil || mcst.trees[1].rollout == nil {
+			t.Fatalf("expected rollout policy %d to make a strategy for each worker", i)
+		}
+		if !strings.Contains(mcst.Config(), "rollout="+policy.Name) ||
+			!strings.Contains(mcst.Config(), "evaluator="+DefaultEvaluator.String()) {
+			t.Fatalf("expected the config to name the rollout policy and evaluator, got %s", mcst.Config())
+		}
+	}
+}
+
+// BenchmarkRolloutPolicies plays McstBots with each rollout policy against
+// one with random rollouts, both thinking for the same time, and reports the
+// fraction of games they won. Run it with a fixed number of games, like
+// -bench RolloutPolicies -benchtime 20x.
+func BenchmarkRolloutPolicies(b *testing.B) {
+	policies := []struct {
+		name   string
+		policy *RolloutPolicy
+	}{
+		{"Random", RandomRollout},
+		{"Attack", AttackRollout},
+		{"Greedy", GreedyRollout},
+		{"EpsilonGreedy", EpsilonGreedyRollout(0.2, AttackRollout)},
+	}
+	for _, p := range policies {
+		b.Run(p.name, func(b *testing.B) {
+			wins := 0
+			for i := 0; i < b.N; i++ {
+				bots := [2]*McstBot{NewMcstBot(), NewMcstBot()}
+				for _, bot := range bots {
+					bot.CalculationTime = 0.1
+					bot.Quiet = true
+				}
+				bots[0].Rollout = p.policy
+				decks := [2]*Deck{Stompy(), MonoBlueDelver()}
+				me := PlayerId(i % 2)
+				opponent := PlayerId(1 - i%2)
+				g := NewGame(decks[(i/2)%2], decks[1-(i/2)%2], int64(i))
+				strategies := [2]Strategy{}
+				strategies[me], strategies[opponent] = bots[0], bots[1]
+				if PlayGame(g, strategies[0], strategies[1], false) == me {
+					wins++
+				}
+			}
+			b.ReportMetric(float64(wins)/float64(b.N), "wins/game")
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	config := DefaultBotConfig(37)
+	config.C = 1.5
+	config.Playouts = 10
+	strategy, err := NewStrategy("mcst", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mb := strategy.(*McstBot)
+	if mb.C != 1.5 || mb.Playouts != 10 || mb.Rng.State != uint64(37) {
+		t.Fatal("expected the McstBot to be configured")
+	}
+	strategy, _ = NewStrategy("mcst", BotConfig{Seed: 37})
+	if mb := strategy.(*McstBot); mb.C != NewMcstBot().C || mb.CalculationTime != NewMcstBot().CalculationTime {
+		t.Fatal("expected the McstBot to use its defaults for the parameters that aren't set")
+	}
+	for _, name := range StrategyNames() {
+		if _, err := NewStrategy(name, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strategy, _ := NewStrategy("external", config); strategy.(*ExternalBot).Err == nil {
+		t.Fatal("expected an external bot without a command to fail")
+	}
+	if _, err := NewStrategy("nobody", config); err == nil {
+		t.Fatal("expected an unknown strategy to be an error")
+	}
+
+	for _, name := range []string{"stompy", "Delver", "../decks/delver.txt"} {
+		if deck, err := LoadDeck(name); err != nil || len(deck.Cards) == 0 {
+			t.Fatalf("expected to load a deck for %s, got %v", name, err)
+		}
+	}
+	if _, err := LoadDeck("nothing"); err == nil {
+		t.Fatal("expected an unknown deck to be an error")
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	skirge := NewEmptyDeck()
+	skirge.Add(1, VaultSkirge)
+	skirge.Add(59, Island)
+
+	counter := NewEmptyDeck()
+	counter.Add(1, Counterspell)
+	counter.Add(59, Island)
+
+	g := newTestGame(counter, skirge)
+	g.Log = &EventLog{}
+	g.playLand()
+	g.passTurn()
+	g.playLand()
+	g.passTurn()
+	g.playLand()
+	g.passTurn()
+	g.putCreatureOnStackAndPass()
+	g.playInstant()
+
+	found := map[string]bool{}
+	for _, e := range g.Log.Events {
+		found[e.String()[strings.Index(e.String(), ":")+2:]] = true
+	}
+	for _, s := range []string{
+		"Island goes from library to hand",
+		"Island goes from hand to battlefield",
+		"Vault Skirge goes from hand to stack",
+		"Counterspell resolves",
+		"Vault Skirge is countered",
+		"Vault Skirge goes from stack to graveyard",
+		"life -2 to 18",
+	} {
+		if !found[s] {
+			t.Fatalf("expected the event %q in the log", s)
+		}
+	}
+}
+
+func TestEventLogDamage(t *testing.T) {
+	bears := NewEmptyDeck()
+	bears.Add(2, GrizzlyBears)
+	bears.Add(58, Forest)
+	g := newTestGame(bears, deckWithTopAndForests(GrizzlyBears))
+	g.Log = &EventLog{}
+	g.playLand()
+	g.passTurn()
+	g.playLand()
+	g.passTurn()
+	g.playLand()
+	g.playCreature()
+	g.passTurn()
+	g.playLand()
+	g.playCreature()
+	g.passTurn()
+
+	// the bears trade in combat
+	g.playLand()
+	g.passUntilPhase(DeclareAttackers)
+	g.attackWithEveryone()
+	for _, a := range g.Actions(false) {
+		if a.Type == Block {
+			g.TakeAction(a)
+			break
+		}
+	}
+	g.passUntilPhase(Main2)
+	if len(g.Creatures()) != 0 {
+		t.Fatal("expected the bears to kill each other")
+	}
+	g.playCreature()
+	g.passTurn()
+	g.passTurn()
+	g.passUntilPhase(DeclareAttackers)
+	g.attackWithEveryone()
+	g.passUntilPhase(Main2)
+
+	dealt := map[PlayerId]int{}
+	for _, e := range g.Log.Events {
+		if e.Type != DamageEvent {
+			continue
+		}
+		if e.Permanent == NoPermanentId {
+			if e.Player != OnTheDraw || e.Amount != 2 {
+				t.Fatalf("expected the unblocked bear to deal 2 damage to player 1, got %s", e)
+			}
+		} else if e.Card != GrizzlyBears || e.Amount != 2 {
+			t.Fatalf("expected lethal damage to a bear, got %s", e)
+		}
+		dealt[e.Player]++
+	}
+	if dealt[OnThePlay] != 1 || dealt[OnTheDraw] != 2 {
+		t.Fatalf("expected the bears that died and the player to be dealt damage, got %v", dealt)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	g := LoggedGame(Stompy(), MonoBlueDelver(), 17)
+	PlayGame(g, NewRandomBot(1), &AttackBot{}, false)
+	replay := g.Log.Replay
+	path := filepath.Join(t.TempDir(), "replay.json")
+	if err := replay.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadReplay(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	games, events, err := loaded.Play()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != len(replay.Steps)+1 || len(events) != len(replay.Steps) {
+		t.Fatalf("expected a game for each of the %d steps", len(replay.Steps))
+	}
+	if games[len(games)-1].Hash() != g.Hash() {
+		t.Fatal("expected the replay to end the same way as the game")
+	}
+	n := 0
+	for _, stepEvents := range events {
+		n += len(stepEvents)
+	}
+	if n != len(g.Log.Events) {
+		t.Fatalf("expected the replay to log the same %d events, got %d", len(g.Log.Events), n)
+	}
+}
+
+func TestTryTakeAction(t *testing.T) {
+	g := newTestGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
+	before := g.Hash()
+	for _, action := range []*Action{
+		nil,
+		{Type: Attack, With: PermanentId(1)},
+		{Type: Play, Card: Counterspell.Card()},
+	} {
+		err := g.TryTakeAction(action)
+		var actionErr *ActionError
+		if !errors.As(err, &actionErr) {
+			t.Fatalf("expected an ActionError, got %v", err)
+		}
+		if action == nil && !errors.Is(err, ErrNoAction) || action != nil && !errors.Is(err, ErrIllegalAction) {
+			t.Fatalf("wrong reason: %v", err)
+		}
+	}
+	if g.Hash() != before {
+		t.Fatal("expected illegal actions not to change the game")
+	}
+
+	// an action from another process has its own copy of the card
+	var land *Action
+	for _, a := range g.Actions(false) {
+		if a.Type == Play && a.Card.IsLand() {
+			land = a
+		}
+	}
+	bytes, _ := json.Marshal(land)
+	copied := &Action{}
+	json.Unmarshal(bytes, copied)
+	if err := g.TryTakeAction(copied); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Priority().Lands()) != 1 {
+		t.Fatal("expected the land to be played")
+	}
+
+	PlayGame(g, &AttackBot{}, &AttackBot{}, false)
+	if err := g.Validate(&Action{Type: PassPriority}); !errors.Is(err, ErrGameOver) {
+		t.Fatalf("expected the game to be over, got %v", err)
+	}
+}
+
+func TestActionNotation(t *testing.T) {
+	for seed := int64(1); seed <= 2; seed++ {
+		g := NewGame(Stompy(), MonoBlueDelver(), seed)
+		bot := NewRandomBot(seed)
+		for !g.IsOver() {
+			for _, a := range g.legalActions() {
+				s := g.FormatAction(a)
+				parsed, err := g.ParseAction(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parsed.hash() != a.hash() {
+					t.Fatalf("%q parsed as %q", s, g.FormatAction(parsed))
+				}
+				bytes, _ := json.Marshal(g.EncodeAction(a))
+				code := ActionCode{}
+				json.Unmarshal(bytes, &code)
+				if code.String() != s {
+					t.Fatalf("%s encoded as JSON came back as %q", s, code)
+				}
+			}
+			g.TakeAction(bot.Action(g))
+		}
+	}
+
+	g := newTestGame(deckWithTopAndForests(GrizzlyBears), deckWithTopAndForests(GrizzlyBears))
+	if action, err := g.ParseAction("T0 M1 play forest"); err != nil || action.Card.Name != Forest {
+		t.Fatalf("expected to play a Forest, got %v", err)
+	}
+	if _, err := g.ParseAction("T1 M1 play Forest"); !errors.Is(err, ErrStaleAction) {
+		t.Fatalf("expected an action for another turn to be stale, got %v", err)
+	}
+	for _, s := range []string{"", "T0 M1", "fly Forest", "cast Nonsense", "attack #x", "cast Rancor -> q1", "choose cards=[Island"} {
+		if _, err := ParseActionCode(s); err == nil {
+			t.Fatalf("expected %q not to parse", s)
+		}
+	}
+}
+
+func TestViewFor(t *testing.T) {
+	ponder := NewEmptyDeck()
+	ponder.Add(1, Ponder)
+	ponder.Add(59, Island)
+
+	rancor := NewEmptyDeck()
+	rancor.Add(1, Rancor)
+	rancor.Add(59, Forest)
+
+	g := newTestGame(ponder, rancor)
+	g.playLand()
+	g.playSorcery()
+
+	// Ponder looks at the top three cards before choosing what to do with them
+	view := g.ViewFor(OnThePlay)
+	if len(view.Actions) == 0 || view.Choice == "" {
+		t.Fatal("expected the player to see their choice")
+	}
+	if view.Players[0].HandSize != 5 || len(view.Players[0].Hand) != 5 || view.Players[1].Hand != nil {
+		t.Fatal("expected to see only your own hand")
+	}
+	bytes, _ := json.Marshal(view)
+	if strings.Contains(string(bytes), string(Rancor)) {
+		t.Fatal("expected not to see the opponent's Rancor")
+	}
+
+	opponentView := g.ViewFor(OnTheDraw)
+	if opponentView.Actions != nil || opponentView.Players[0].Hand != nil ||
+		opponentView.Players[1].HandSize != 7 || opponentView.Players[0].LibrarySize != 53 {
+		t.Fatal("expected the opponent to see counts but no choices")
+	}
+	bytes, _ = json.Marshal(opponentView)
+	if !strings.Contains(string(bytes), string(Rancor)) {
+		t.Fatal("expected the opponent to see their own Rancor")
+	}
+
+	for _, a := range g.Actions(false) {
+		if a.AfterEffect != nil && a.AfterEffect.EffectType == ReturnCardsToTopDraw {
+			g.TakeAction(a)
+			break
+		}
+	}
+	if g.ViewFor(OnTheDraw).Players[0].KnownTop != 2 {
+		t.Fatal("expected the opponent to see that two cards Ponder looked at are still on top")
+	}
+}
+
+func TestViewForKnownBottom(t *testing.T) {
+	preordain := NewEmptyDeck()
+	preordain.Add(1, Preordain)
+	preordain.Add(6, Island)
+	preordain.Add(1, GrizzlyBears)
+	preordain.Add(1, NettleSentinel)
+	preordain.Add(51, Island)
+
+	g := newTestGame(preordain, deckWithTopAndForests(GrizzlyBears))
+	g.playLand()
+	g.playSorcery()
+	for _, a := range g.Actions(false) {
+		if a.AfterEffect != nil && len(a.AfterEffect.ScryCards[1]) == 2 {
+			g.TakeAction(a)
+			break
+		}
+	}
+
+	known := g.ViewFor(OnThePlay).Players[0].KnownBottomLibrary
+	if len(known) != 2 || indexOf(known, GrizzlyBears) < 0 || indexOf(known, NettleSentinel) < 0 {
+		t.Fatalf("expected to see the two cards scried to the bottom, got %v", known)
+	}
+	opponentView := g.ViewFor(OnTheDraw).Players[0]
+	if opponentView.KnownBottomLibrary != nil || opponentView.KnownBottom != 2 {
+		t.Fatal("expected the opponent to see only how many cards are known on the bottom")
+	}
+}
+
+func TestViewForTemporaryEffects(t *testing.T) {
+	g := newTestGame(topNettleVines(), deckWithTopAndForests(GrizzlyBears))
+	g.playLand()
+	g.playCreature()
+	g.passTurn()
+	g.passTurn()
+	g.playLand()
+	g.passTurn()
+	g.passTurn()
+
+	g.playLand()
+	g.playKickedInstant()
+	nettle := g.Priority().GetCreature(NettleSentinel)
+	for _, perm := range g.ViewFor(OnTheDraw).Permanents {
+		if perm.Id == nettle.Id {
+			if len(perm.TemporaryEffects) != 1 || perm.TemporaryEffects[0] != "+4/+4, untargetable" {
+				t.Fatalf("expected kicked Vines of Vastwood to be described, got %v", perm.TemporaryEffects)
+			}
+			return
+		}
+	}
+	t.Fatal("expected to see the Nettle Sentinel")
+}
+
+// TestExternalBotProgram is the program that TestExternalBot runs, when
+// ROGUE_TEST_BOT says how it should play.
+func TestExternalBotProgram(t *testing.T) {
+	mode := os.Getenv("ROGUE_TEST_BOT")
+	if mode == "" {
+		return
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "rogue "):
+			fmt.Println("ready")
+		case strings.HasPrefix(line, "state "):
+			view := &View{}
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "state ")), view)
+			switch mode {
+			case "first":
+				fmt.Println("info choosing the first action")
+				fmt.Println("action 0")
+			case "last":
+				fmt.Println("action", view.Actions[len(view.Actions)-1])
+			case "illegal":
+				fmt.Println("action attack #999")
+			case "slow":
+				time.Sleep(time.Minute)
+			}
+		case line == "quit":
+			os.Exit(0)
+		}
+	}
+	os.Exit(0)
+}
+
+func TestExternalBot(t *testing.T) {
+	newBot := func(mode string) *ExternalBot {
+		os.Setenv("ROGUE_TEST_BOT", mode)
+		b := NewExternalBot(os.Args[0] + " -test.run=^TestExternalBotProgram$")
+		b.Quiet = true
+		return b
+	}
+	defer os.Unsetenv("ROGUE_TEST_BOT")
+
+	for _, mode := range []string{"first", "last"} {
+		b := newBot(mode)
+		g := NewGame(Stompy(), MonoBlueDelver(), 19)
+		PlayGame(g, b, &AttackBot{}, false)
+		if b.Err != nil {
+			t.Fatal(b.Err)
+		}
+		if err := b.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, mode := range []string{"illegal", "slow"} {
+		b := newBot(mode)
+		b.Timeout = 200 * time.Millisecond
+		g := NewGame(Stompy(), MonoBlueDelver(), 19)
+		if err := g.Validate(b.Action(g)); err != nil || b.Err == nil {
+			t.Fatalf("expected the %s program to fail and the fallback to play, got %v", mode, err)
+		}
+		b.Close()
+	}
+
+	b := NewExternalBot("./no-such-bot")
+	g := NewGame(Stompy(), MonoBlueDelver(), 19)
+	if b.Action(g) == nil || b.Err == nil {
+		t.Fatal("expected a missing program to fail")
+	}
+}
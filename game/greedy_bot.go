@@ -0,0 +1,52 @@
+package game
+
+/*
+	GreedyBot is a Strategy that looks one action ahead, and takes the action
+	that leaves it in the best position according to its Evaluator.
+	Most actions, like tapping a land, don't change the position, so it
+	chooses randomly between the actions that are tied for best.
+*/
+
+type GreedyBot struct {
+	// Evaluator is the DefaultEvaluator if it is not set.
+	Evaluator Evaluator
+	// Rng is seeded from the first game the bot plays if it is not set.
+	Rng *Rng
+}
+
+func NewGreedyBot(seed int64) *GreedyBot {
+	return &GreedyBot{Evaluator: DefaultEvaluator, Rng: NewRng(seed)}
+}
+
+func (b *GreedyBot) String() string {
+	return "GreedyBot"
+}
+
+func (b *GreedyBot) Action(g *Game) *Action {
+	if b.Rng == nil {
+		b.Rng = NewRng(botSeed(g))
+	}
+	if b.Evaluator == nil {
+		b.Evaluator = DefaultEvaluator
+	}
+	actions := g.Actions(false)
+	if len(actions) == 1 {
+		return actions[0]
+	}
+
+	me := g.PriorityId
+	best := []*Action{}
+	bestScore := 0.0
+	for _, action := range actions {
+		clone := g.Clone()
+		clone.TakeAction(action)
+		score := b.Evaluator.Evaluate(clone, me)
+		if len(best) == 0 || score > bestScore {
+			best = []*Action{action}
+			bestScore = score
+		} else if score == bestScore {
+			best = append(best, action)
+		}
+	}
+	return best[b.Rng.Intn(len(best))]
+}
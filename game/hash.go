@@ -0,0 +1,355 @@
+/*
+	Hashes of game states, for bots that search the game and need to look up
+	the states they have seen before. This is a lot faster than comparing
+	serialized games.
+
+	The hash is Zobrist-style: each feature of the state, like a tapped
+	permanent or a card in a graveyard, has its own random number, and the hash
+	is the sum of the numbers of the features the state has. Sums are used
+	instead of XORs so that two copies of the same card in a hand don't cancel
+	out. A feature's random number is a hash of what the feature is, rather
+	than an entry in a table, so games can have any number of permanents.
+
+	The sums for the permanents, the stack and each zone are kept up to date by
+	the methods that change them, so getting the hash doesn't walk the whole
+	state. Anything that changes a permanent calls its rehash, which swaps its
+	old number in the sum for its new one. The rest of the state, like phases
+	and life totals, is small enough to hash when the hash is asked for.
+*/
+
+package game
+
+// The kinds of features, so the same numbers in different parts of the
+// state make different features.
+const (
+	gameFeature uint64 = iota + 1
+	playerFeature
+	handFeature
+	revealedFeature
+	deckFeature
+	graveyardFeature
+	exileFeature
+	boardFeature
+	permanentFeature
+	stackObjectFeature
+	choiceEffectFeature
+	rngFeature
+	revealedLibraryFeature
+)
+
+// Hash returns a hash of the whole state of the game, including the cards in
+// hands, the order of the libraries and the Rng.
+func (g *Game) Hash() uint64 {
+	return g.hash(NoPlayerId)
+}
+
+// InformationSetHash returns a hash that is the same for two states of the
+// game if the player with the given id can't tell them apart.
+func (g *Game) InformationSetHash(id PlayerId) uint64 {
+	return g.hash(id)
+}
+
+// hash hashes the state as the player with the given id sees it, or the whole
+// state for NoPlayerId.
+func (g *Game) hash(viewer PlayerId) uint64 {
+	sum := feature(gameFeature, uint64(g.Phase), uint64(g.Turn), uint64(g.PriorityId),
+		uint64(g.NextPermanentId), uint64(g.NextStackObjectId),
+		boolHash(g.ActorPassedOnStack), boolHash(g.DeclarationFinished))
+	sum += g.permanentsHash + g.stackHash
+	if viewer == NoPlayerId && g.Rng != nil {
+		sum += feature(rngFeature, g.Rng.State)
+	}
+	for _, p := range g.Players {
+		sum += p.hash(viewer)
+	}
+	if g.ChoiceEffect != nil {
+		sum += feature(choiceEffectFeature, g.ChoiceEffect.hash())
+	}
+	return sum
+}
+
+func (p *Player) hash(viewer PlayerId) uint64 {
+	id := uint64(p.Id)
+	sum := feature(playerFeature, id, uint64(p.Life), p.ManaPool.hash(),
+		boolHash(p.CreatureDied), uint64(p.DamageThisTurn), boolHash(p.KeptHand),
+		uint64(p.LandPlayedThisTurn), uint64(p.Mulligans), uint64(len(p.Hand)),
+		uint64(len(p.Deck.Cards)), boolHash(p.Deck.FailedToDraw), uint64(p.Deck.KnownTop),
+		uint64(p.Deck.KnownBottom), uint64(p.Deck.RevealedTop))
+
+	// an opponent only sees the revealed cards in a hand
+	if viewer == NoPlayerId || viewer == p.Id {
+		sum += feature(handFeature, id, p.handHash)
+	}
+	sum += feature(revealedFeature, id, p.revealedHash)
+
+	// a player knows the order of the cards they looked at on top of their
+	// library and put on the bottom of it
+	if viewer == NoPlayerId {
+		sum += feature(deckFeature, id, p.Deck.cardsHash)
+	} else if viewer == p.Id {
+		sum += feature(deckFeature, id, p.Deck.knownHash())
+	} else {
+		sum += feature(revealedLibraryFeature, id, p.Deck.revealedHash())
+	}
+
+	sum += feature(graveyardFeature, id, p.graveyardHash)
+	sum += feature(exileFeature, id, p.exileHash)
+	sum += feature(boardFeature, id, p.boardHash)
+	return sum
+}
+
+// rehash recomputes the sums that the changes to the game keep up to date,
+// for a game that was put together some other way, like by DeserializeGame.
+func (g *Game) rehash() {
+	g.permanentsHash = 0
+	for _, perm := range g.Permanents {
+		perm.hashed = 0
+		perm.rehash()
+	}
+	g.rehashStack()
+	for _, p := range g.Players {
+		p.rehash()
+	}
+}
+
+// rehash updates the game's permanentsHash after the permanent changed.
+// It does nothing for a permanent that isn't in the game.
+func (c *Permanent) rehash() {
+	if c.game == nil || c.game.Permanents[c.Id] != c {
+		return
+	}
+	h := c.hash()
+	c.game.permanentsHash += h - c.hashed
+	c.hashed = h
+}
+
+// rehashStack recomputes the stackHash. Objects are only ever added on top of
+// the stack, but they can be removed from the middle of it.
+func (g *Game) rehashStack() {
+	g.stackHash = 0
+	for i, id := range g.Stack {
+		g.stackHash += feature(stackObjectFeature, uint64(i), g.StackObjects[id].hash())
+	}
+}
+
+func (p *Player) rehash() {
+	p.handHash = sumOf(handFeature, p.Hand)
+	p.revealedHash = sumOf(revealedFeature, p.Revealed)
+	p.rehashGraveyard()
+	p.exileHash = 0
+	for i, name := range p.Exile {
+		p.exileHash += cardFeature(exileFeature, i, name)
+	}
+	p.boardHash = 0
+	for _, id := range p.Board {
+		p.boardHash += feature(boardFeature, uint64(id))
+	}
+	p.Deck.rehash()
+}
+
+func (p *Player) rehashGraveyard() {
+	p.graveyardHash = 0
+	for i, name := range p.Graveyard {
+		p.graveyardHash += cardFeature(graveyardFeature, i, name)
+	}
+}
+
+// Library positions are counted from the bottom, so drawing a card doesn't
+// change the features of the others.
+func (d *Deck) rehash() {
+	d.cardsHash = 0
+	for i, name := range d.Cards {
+		d.cardsHash += cardFeature(deckFeature, len(d.Cards)-1-i, name)
+	}
+}
+
+// revealedHash is the part of the cardsHash that both players know.
+func (d *Deck) revealedHash() uint64 {
+	sum := uint64(0)
+	for i, name := range d.Cards[:Min(d.RevealedTop, len(d.Cards))] {
+		sum += cardFeature(deckFeature, len(d.Cards)-1-i, name)
+	}
+	return sum
+}
+
+// knownHash is the part of the cardsHash that the deck's owner knows.
+func (d *Deck) knownHash() uint64 {
+	sum := uint64(0)
+	top := Min(d.KnownTop, len(d.Cards))
+	for i, name := range d.Cards[:top] {
+		sum += cardFeature(deckFeature, len(d.Cards)-1-i, name)
+	}
+	for i := Max(top, len(d.Cards)-d.KnownBottom); i < len(d.Cards); i++ {
+		sum += cardFeature(deckFeature, len(d.Cards)-1-i, d.Cards[i])
+	}
+	return sum
+}
+
+func (p *Permanent) hash() uint64 {
+	h := newHasher(permanentFeature)
+	h.add(uint64(p.Id), nameHash(p.Name), uint64(p.Owner), boolHash(p.ActivatedThisTurn),
+		boolHash(p.Tapped), uint64(p.TurnPlayed), boolHash(p.Attacking),
+		uint64(p.Blocking), uint64(p.Damage), uint64(p.Plus1Plus1Counters),
+		uint64(p.Target))
+	h.addIds(p.Auras)
+	h.addIds(p.DamageOrder)
+	for _, e := range p.TemporaryEffects {
+		h.add(e.hash())
+	}
+	return h.sum()
+}
+
+// The hash of an Action is the same in every determinization of a game.
+func (a *Action) hash() uint64 {
+	h := newHasher(uint64(a.Type))
+	h.add(uint64(a.EntersTheBattleFieldSpellTarget), uint64(a.Color),
+		boolHash(a.ShouldSwitchPriority), uint64(a.Source), a.Target.hash(),
+		uint64(a.With), boolHash(a.WithAlternate), boolHash(a.WithKicker),
+		boolHash(a.WithNinjitsu), boolHash(a.WithPhyrexian), a.Cost.hash(),
+		a.AfterEffect.hash())
+	if a.Card != nil {
+		h.add(nameHash(a.Card.Name))
+	}
+	h.addIds(a.Selected)
+	return h.sum()
+}
+
+func (s *StackObject) hash() uint64 {
+	h := newHasher(stackObjectFeature)
+	h.add(uint64(s.Type), uint64(s.EntersTheBattleFieldSpellTarget), uint64(s.Id),
+		uint64(s.Player), uint64(s.Source), s.Target.hash(), boolHash(s.WithNinjitsu))
+	if s.Card != nil {
+		h.add(nameHash(s.Card.Name))
+	}
+	h.add(s.Cost.hash(), s.Kicker.hash())
+	h.addIds(s.Selected)
+	return h.sum()
+}
+
+// The hash of an Effect only covers the properties that change during a
+// game; the rest comes from the card it is an effect of.
+func (e *Effect) hash() uint64 {
+	if e == nil {
+		return 0
+	}
+	h := newHasher(uint64(e.EffectType))
+	h.add(uint64(e.Damage), boolHash(e.Hexproof), e.Mana.hash(),
+		uint64(e.Plus1Plus1Counters), uint64(e.Power), uint64(e.Toughness),
+		boolHash(e.Untargetable), nameHash(e.Summon), uint64(e.Source),
+		uint64(e.SelectedForCost), e.Target.hash(), e.Cost.hash(), e.Kicker.hash())
+	for _, name := range e.Cards {
+		h.add(nameHash(name))
+	}
+	for _, names := range e.ScryCards {
+		h.add(uint64(len(names)))
+		for _, name := range names {
+			h.add(nameHash(name))
+		}
+	}
+	h.addIds(e.Selected)
+	return h.sum()
+}
+
+func (c *Cost) hash() uint64 {
+	if c == nil {
+		return 0
+	}
+	h := newHasher(uint64(c.Generic))
+	h.add(uint64(c.Life), c.Mana.hash(), c.Effect.hash())
+	for _, colors := range c.Hybrid {
+		h.add(uint64(len(colors)))
+		for _, color := range colors {
+			h.add(uint64(color))
+		}
+	}
+	for _, color := range c.Phyrexian {
+		h.add(uint64(color))
+	}
+	return h.sum()
+}
+
+func (t Target) hash() uint64 {
+	return feature(uint64(t.Type), uint64(t.Player), uint64(t.Permanent), uint64(t.StackObject))
+}
+
+func (m Mana) hash() uint64 {
+	h := newHasher(0)
+	for _, amount := range m {
+		h.add(uint64(amount))
+	}
+	return h.sum()
+}
+
+// A hasher combines numbers into a hash, in order.
+type hasher uint64
+
+func newHasher(kind uint64) hasher {
+	h := hasher(0)
+	h.add(kind)
+	return h
+}
+
+func (h *hasher) add(values ...uint64) {
+	for _, v := range values {
+		*h = hasher(mix64(uint64(*h) ^ v))
+	}
+}
+
+func (h *hasher) addIds(ids []PermanentId) {
+	h.add(uint64(len(ids)))
+	for _, id := range ids {
+		h.add(uint64(id))
+	}
+}
+
+func (h *hasher) sum() uint64 {
+	return uint64(*h)
+}
+
+// feature returns the random number of a feature, given as its kind and the
+// numbers that say what it is.
+func feature(kind uint64, values ...uint64) uint64 {
+	h := newHasher(kind)
+	h.add(values...)
+	return h.sum()
+}
+
+// cardFeature returns the random number of a card at a position in a zone.
+func cardFeature(kind uint64, position int, name CardName) uint64 {
+	return feature(kind, uint64(position), nameHash(name))
+}
+
+// sumOf returns the sum of the features of cards in a zone where their order
+// doesn't matter, like a hand.
+func sumOf(kind uint64, names []CardName) uint64 {
+	sum := uint64(0)
+	for _, name := range names {
+		sum += feature(kind, nameHash(name))
+	}
+	return sum
+}
+
+// mix64 is the finalizer of splitmix64, which spreads each bit of its input
+// over all the bits of its output.
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// nameHash is the FNV-1a hash of a card name.
+func nameHash(name CardName) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(name); i++ {
+		h ^= uint64(name[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+func boolHash(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
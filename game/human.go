@@ -40,7 +40,8 @@ func promptForAction(game *Game, actions []*Action) *Action {
 		}
 		fmt.Printf("## Turn %d | %s (%s)\n", game.Turn, game.Phase, whoseTurn)
 		for index, action := range actions {
-			if index == len(actions)-1 && (actions[len(actions)-1].Type == Pass || actions[len(actions)-1].Type == PassPriority) {
+			lastType := actions[len(actions)-1].Type
+			if index == len(actions)-1 && (lastType == Pass || lastType == PassPriority || lastType == FinishDeclaration) {
 				fmt.Printf("enter) %s\n", action.ShowTo(player))
 			} else {
 				fmt.Printf("%d) %s\n", index+1, action.ShowTo(player))
@@ -98,3 +99,69 @@ func promptForTargetAndMana(allowSorcerySpeed bool, game *Game, action *Action)
 		}
 	}
 }
+
+// ChooseToPlay asks the human whether to play first in the next game of a match.
+func (h *Human) ChooseToPlay(m *Match, side int) bool {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("\nDo you want to play first? (y/n): ")
+		text, _ := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(text)) {
+		case "y", "":
+			return true
+		case "n":
+			return false
+		}
+	}
+}
+
+// Sideboard asks the human which cards to swap with their sideboard before the
+// next game of a match.
+func (h *Human) Sideboard(m *Match, side int) []SideboardSwap {
+	swaps := []SideboardSwap{}
+	deck := m.Decks[side].Copy()
+	reader := bufio.NewReader(os.Stdin)
+	for len(deck.Sideboard) > 0 {
+		main := distinctCardNames(deck.Cards)
+		sideboard := distinctCardNames(deck.Sideboard)
+		fmt.Println("\nDeck:")
+		for index, name := range main {
+			fmt.Printf("%d) %d %s\n", index+1, countOf(deck.Cards, name), name)
+		}
+		fmt.Println("Sideboard:")
+		for index, name := range sideboard {
+			fmt.Printf("%d) %d %s\n", index+1, countOf(deck.Sideboard, name), name)
+		}
+		fmt.Print("\nEnter a deck card and a sideboard card to swap, like \"1 2\", or enter when done: ")
+		text, _ := reader.ReadString('\n')
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			return swaps
+		}
+		if len(fields) != 2 {
+			continue
+		}
+		out, err1 := strconv.Atoi(fields[0])
+		in, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil || out < 1 || out > len(main) || in < 1 || in > len(sideboard) {
+			continue
+		}
+		swap := SideboardSwap{Out: main[out-1], In: sideboard[in-1]}
+		if deck.Swap(swap.Out, swap.In) {
+			swaps = append(swaps, swap)
+		}
+	}
+	return swaps
+}
+
+func distinctCardNames(names []CardName) []CardName {
+	seen := make(map[CardName]bool)
+	answer := []CardName{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			answer = append(answer, name)
+		}
+	}
+	return answer
+}
@@ -0,0 +1,48 @@
+/*
+	A player can't see their opponent's hand or the order of either library, so
+	a bot that searches the game should only use what its player could know.
+
+	Determinize makes a copy of the game where that hidden information is
+	re-sampled, and InformationSetHash in hash.go is the same for all the
+	states a player can't tell apart.
+*/
+
+package game
+
+// Determinize returns a copy of the game where everything the player with the
+// given id can't see is shuffled: the unrevealed cards in their opponent's
+// hand with their opponent's library below the cards revealed on top of it,
+// and their own library between the cards
+// they know the order of on top and on the bottom. The copy gets its own Rng, seeded from rng, so its
+// future shuffles are re-sampled too.
+func (g *Game) Determinize(id PlayerId, rng *Rng) *Game {
+	clone := g.Clone()
+	clone.Rng = NewRng(rng.Int63())
+
+	// this shuffles the cards of the player's deck between the ones they know
+	unknown := &Deck{Cards: clone.Player(id).Deck.Unknown()}
+	unknown.Shuffle(rng)
+
+	opponent := clone.Player(id).Opponent()
+	hiddenHand := unrevealed(opponent)
+	handSize := len(hiddenHand)
+	revealedTop := opponent.Deck.Cards[:Min(opponent.Deck.RevealedTop, len(opponent.Deck.Cards))]
+	hidden := &Deck{Cards: append(hiddenHand, opponent.Deck.Cards[len(revealedTop):]...)}
+	hidden.Shuffle(rng)
+	opponent.Hand = append(append([]CardName{}, opponent.Revealed...), hidden.Cards[:handSize]...)
+	opponent.Deck.Cards = append(copyCardNames(revealedTop), hidden.Cards[handSize:]...)
+	clone.Player(id).rehash()
+	opponent.rehash()
+	return clone
+}
+
+// unrevealed returns the cards in the player's hand that aren't Revealed.
+func unrevealed(p *Player) []CardName {
+	hand := append([]CardName{}, p.Hand...)
+	for _, name := range p.Revealed {
+		if i := indexOf(hand, name); i >= 0 {
+			hand = append(hand[:i], hand[i+1:]...)
+		}
+	}
+	return hand
+}
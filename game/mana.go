@@ -0,0 +1,142 @@
+/*
+	Mana comes in five colors plus colorless.
+
+	Lands and other mana producers say which types of mana they can make, a
+	Player's ManaPool holds how much of each type is floating, and a Cost says
+	which types it needs. Paying a Cost means finding a way to match each mana
+	symbol of the Cost with a mana from the pool or an untapped land.
+*/
+
+package game
+
+import (
+	"strings"
+)
+
+//go:generate stringer -type=Color
+type Color int
+
+// Keep Colorless last, it is used to size Mana.
+const (
+	White Color = iota
+	Blue
+	Black
+	Red
+	Green
+	Colorless
+)
+
+const numColors = int(Colorless) + 1
+
+// AllColors is every type of mana, for symbols like generic mana that any of
+// them can pay.
+var AllColors = []Color{White, Blue, Black, Red, Green, Colorless}
+
+// Symbol returns the letter for the color used in mana costs, like "G".
+func (c Color) Symbol() string {
+	return "WUBRGC"[c : c+1]
+}
+
+// Mana is an amount of each type of mana, indexed by Color.
+// For example, Mana{Red: 1, Green: 1} is RG.
+type Mana [numColors]int
+
+// Total returns the amount of mana regardless of type.
+func (m Mana) Total() int {
+	total := 0
+	for _, amount := range m {
+		total += amount
+	}
+	return total
+}
+
+// Plus returns the sum of m and other.
+func (m Mana) Plus(other Mana) Mana {
+	for c, amount := range other {
+		m[c] += amount
+	}
+	return m
+}
+
+func (m Mana) String() string {
+	s := ""
+	for c, amount := range m {
+		s += strings.Repeat(Color(c).Symbol(), amount)
+	}
+	return s
+}
+
+// manaSource is one mana a player could use to pay a cost: either a mana in
+// their pool, or an untapped land that can make one of several colors.
+type manaSource struct {
+	colors []Color
+	land   PermanentId // NoPermanentId for mana in the pool
+}
+
+// manaSymbol is one symbol of a cost, which any of colors can pay.
+type manaSymbol struct {
+	colors []Color
+}
+
+func (s manaSymbol) canBePaidBy(source manaSource) bool {
+	for _, c := range s.colors {
+		for _, sc := range source.colors {
+			if c == sc {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findManaPayment matches every symbol to a different source.
+//
+// It returns, for each symbol, the index of the source that pays it, or nil
+// if there is no way to pay all of the symbols. Earlier sources are preferred,
+// so callers should list the mana pool before lands.
+func findManaPayment(symbols []manaSymbol, sources []manaSource) []int {
+	// Match the most constrained symbols first, so that generic mana does not use
+	// up the only source of a color that a colored symbol needs.
+	order := make([]int, len(symbols))
+	for i := range order {
+		order[i] = i
+	}
+	for i := 1; i < len(order); i++ {
+		for j := i; j > 0 && len(symbols[order[j]].colors) < len(symbols[order[j-1]].colors); j-- {
+			order[j], order[j-1] = order[j-1], order[j]
+		}
+	}
+
+	// A bipartite matching of symbols to sources, by augmenting paths.
+	sourceFor := make([]int, len(symbols))
+	symbolFor := make([]int, len(sources))
+	for i := range sourceFor {
+		sourceFor[i] = -1
+	}
+	for i := range symbolFor {
+		symbolFor[i] = -1
+	}
+
+	var augment func(symbol int, seen []bool) bool
+	augment = func(symbol int, seen []bool) bool {
+		for s, source := range sources {
+			if seen[s] || !symbols[symbol].canBePaidBy(source) {
+				continue
+			}
+			seen[s] = true
+			if symbolFor[s] == -1 || augment(symbolFor[s], seen) {
+				symbolFor[s] = symbol
+				sourceFor[symbol] = s
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, symbol := range order {
+		if !augment(symbol, make([]bool, len(sources))) {
+			return nil
+		}
+	}
+	return sourceFor
+}
@@ -0,0 +1,199 @@
+/*
+	A Match is a series of games between two decks, won by the first side to win
+	GamesToWin games, best-of-three by default.
+
+	The sides of a match are numbered 0 and 1, and keep their number from game
+	to game no matter which of them is on the play.
+*/
+
+package game
+
+import (
+	"fmt"
+)
+
+// MaxCopies is how many copies of a card other than a basic land a deck can
+// have after sideboarding.
+const MaxCopies = 4
+
+// A SideboardSwap takes one copy of Out out of the deck and puts one copy of
+// In in from the sideboard.
+type SideboardSwap struct {
+	Out CardName
+	In  CardName
+}
+
+// A MatchStrategy is a Strategy that also makes the decisions between games
+// of a match. A Strategy that is not a MatchStrategy always chooses to play
+// first and never sideboards.
+type MatchStrategy interface {
+	Strategy
+
+	// ChooseToPlay returns whether side wants to be on the play for the next game.
+	ChooseToPlay(m *Match, side int) bool
+
+	// Sideboard returns the swaps side wants to make to its deck before the
+	// next game.
+	Sideboard(m *Match, side int) []SideboardSwap
+}
+
+type Match struct {
+	// Decks are the decklists for each side, copied from the ones the match
+	// was made with. Each game is played with a shuffled copy, and
+	// sideboarding changes them for the rest of the match.
+	Decks      [2]*Deck
+	Strategies [2]Strategy
+
+	GamesToWin int
+	// MaxGames ends the match when too many games are draws.
+	MaxGames int
+
+	// Winners holds the side that won each game played, or -1 for a draw.
+	Winners []int
+	// OnThePlay holds the side that was on the play for each game played.
+	OnThePlay []int
+	// Choosers holds the side that decided who played first in each game played.
+	Choosers []int
+	// Seeds holds the seed of each game played, to replay it.
+	Seeds []int64
+
+	// Rng flips the coin for the first game and seeds each game.
+	Rng *Rng
+
+	PrintResult bool
+}
+
+// NewMatch makes a match whose games are all seeded from seed. The match
+// sideboards copies of the decks, so the caller's decks don't change.
+func NewMatch(deck0 *Deck, deck1 *Deck, strategy0 Strategy, strategy1 Strategy, seed int64) *Match {
+	return &Match{
+		Decks:      [2]*Deck{deck0.Copy(), deck1.Copy()},
+		Strategies: [2]Strategy{strategy0, strategy1},
+		GamesToWin: 2,
+		MaxGames:   5,
+		Winners:    []int{},
+		OnThePlay:  []int{},
+		Choosers:   []int{},
+		Seeds:      []int64{},
+		Rng:        NewRng(seed),
+	}
+}
+
+// Wins returns how many games side has won.
+func (m *Match) Wins(side int) int {
+	wins := 0
+	for _, winner := range m.Winners {
+		if winner == side {
+			wins++
+		}
+	}
+	return wins
+}
+
+func (m *Match) IsOver() bool {
+	return m.Wins(0) >= m.GamesToWin || m.Wins(1) >= m.GamesToWin || len(m.Winners) >= m.MaxGames
+}
+
+// Winner returns the side that won the match, or -1 if it is not over or is a draw.
+func (m *Match) Winner() int {
+	if !m.IsOver() || m.Wins(0) == m.Wins(1) {
+		return -1
+	}
+	if m.Wins(0) > m.Wins(1) {
+		return 0
+	}
+	return 1
+}
+
+// Play plays games until the match is over, and returns the winner.
+func (m *Match) Play() int {
+	for !m.IsOver() {
+		m.PlayGame()
+	}
+	if m.PrintResult {
+		fmt.Printf("Match result: %s %d - %d %s\n",
+			m.Strategies[0], m.Wins(0), m.Wins(1), m.Strategies[1])
+	}
+	return m.Winner()
+}
+
+// PlayGame sideboards, decides who plays first, and plays the next game of the match.
+func (m *Match) PlayGame() {
+	if len(m.Winners) > 0 {
+		for side := range m.Strategies {
+			m.sideboard(side)
+		}
+	}
+
+	chooser := m.chooser()
+	onThePlay := chooser
+	if ms, ok := m.Strategies[chooser].(MatchStrategy); ok && !ms.ChooseToPlay(m, chooser) {
+		onThePlay = 1 - chooser
+	}
+	onTheDraw := 1 - onThePlay
+
+	seed := m.Rng.Int63()
+	g := NewGame(m.Decks[onThePlay].Copy(), m.Decks[onTheDraw].Copy(), seed)
+
+	winner := PlayGame(g, m.Strategies[onThePlay], m.Strategies[onTheDraw], m.PrintResult)
+	m.OnThePlay = append(m.OnThePlay, onThePlay)
+	m.Choosers = append(m.Choosers, chooser)
+	m.Seeds = append(m.Seeds, seed)
+	switch winner {
+	case OnThePlay:
+		m.Winners = append(m.Winners, onThePlay)
+	case OnTheDraw:
+		m.Winners = append(m.Winners, onTheDraw)
+	default:
+		m.Winners = append(m.Winners, -1)
+	}
+}
+
+// chooser returns the side that decides who plays first in the next game.
+// That is the loser of the last game, or whoever chose last time after a draw.
+// The first game is decided by a coin flip.
+func (m *Match) chooser() int {
+	if len(m.Winners) == 0 {
+		return m.Rng.Intn(2)
+	}
+	if winner := m.Winners[len(m.Winners)-1]; winner != -1 {
+		return 1 - winner
+	}
+	return m.Choosers[len(m.Choosers)-1]
+}
+
+func (m *Match) sideboard(side int) {
+	ms, ok := m.Strategies[side].(MatchStrategy)
+	if !ok {
+		return
+	}
+	for _, swap := range ms.Sideboard(m, side) {
+		if err := m.ApplySwap(side, swap); err != nil && m.PrintResult {
+			fmt.Println(err)
+		}
+	}
+}
+
+// ApplySwap makes a sideboard swap for side, or returns an error without
+// changing the deck if the swap breaks the sideboard rules.
+func (m *Match) ApplySwap(side int, swap SideboardSwap) error {
+	deck := m.Decks[side]
+	if !swap.In.Card().HasSupertype(Basic) && swap.In != swap.Out &&
+		countOf(deck.Cards, swap.In) >= MaxCopies {
+		return fmt.Errorf("cannot have more than %d copies of %s", MaxCopies, swap.In)
+	}
+	if !deck.Swap(swap.Out, swap.In) {
+		return fmt.Errorf("cannot swap %s for %s from the sideboard", swap.Out, swap.In)
+	}
+	return nil
+}
+
+func countOf(names []CardName, name CardName) int {
+	count := 0
+	for _, n := range names {
+		if n == name {
+			count++
+		}
+	}
+	return count
+}
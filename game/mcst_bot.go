@@ -3,152 +3,438 @@ package game
 import (
 	"fmt"
 	"math"
-	"math/rand"
+	"sort"
+	"sync"
 	"time"
 )
 
 /*
 	MCST is a Strategy that implements a monte carlo search tree.
+
+	It only uses what its player can know. Each playout starts from a
+	determinization of the game, where the opponent's hand and both libraries
+	are re-sampled, and the nodes of the tree are information sets rather than
+	states, so the playouts of different determinizations add up. This is
+	single-observer information set MCTS:
+	https://eprints.whiterose.ac.uk/75048/1/CowlingPowleyWhitehouse2012.pdf
+
+	The tree is kept between moves. When the bot has to act again, the node
+	for the new information set becomes the root, and the rest of the tree is
+	dropped.
 */
 
 type McstBot struct {
-	// count times states have been reached in playouts
-	plays map[string]int
-	// count times states that have been reached in playouts led to a win
-	wins map[string]int
-	// cache availble actions for states
-	stateToActions map[string][]*ActionState
-	// the amount of time to call run_simulation as much as possible
-	calculationTime float64
-	// the max_moves for any play out
-	maxMoves int
+	// the search tree of each worker
+	trees []*mcstTree
+	// CalculationTime is how many seconds the bot searches for each action.
+	CalculationTime float64
+	// MaxMoves caps the number of actions in a playout.
+	MaxMoves int
 	/*
 		Larger C encourages more exploration of the possibilities,
 		smaller causes the AI to prefer concentrating on known good moves
 	*/
 	C float64
+	// Rng makes the determinizations and the random choices in playouts, and is
+	// seeded from the game if it is not set.
+	Rng *Rng
+	/*
+		Workers is how many goroutines search at once. Each searches its own
+		tree, and their statistics are added up to choose an action, which is
+		called root parallelization. The first worker uses Rng and the others
+		are seeded from it, so with the same seed, Playouts and Workers, the
+		bot chooses the same action every time. The first worker searches
+		the same as a single worker would, but the added up statistics can
+		favor a different action, so changing Workers can change the choice.
+		It is 1 by default, so the choice doesn't depend on the machine.
+	*/
+	Workers int
+	// If Playouts is positive, each worker does that many playouts instead of
+	// searching for CalculationTime, so the choice only depends on the seeds.
+	Playouts int
+	// MaxNodes caps the number of nodes in all the trees together. When a tree
+	// gets bigger than its share, its least visited branches are pruned. Each
+	// tree's share is at least 2 nodes, so a tree always has room for more
+	// than its root.
+	MaxNodes int
+	/*
+		If PlayoutDepth is positive, a playout stops after that many rollout
+		actions, and the Evaluator's estimate of the game counts instead of
+		who won. Without an Evaluator, playouts go on until the game is over.
+	*/
+	PlayoutDepth int
+	Evaluator    Evaluator
+	// Rollout chooses the actions of playouts after they leave the tree.
+	// They are random if it is not set.
+	Rollout *RolloutPolicy
+	// Quiet stops the bot from printing its statistics for each move.
+	Quiet bool
 }
 
 func NewMcstBot() *McstBot {
 	mcst := &McstBot{
 		C:               3,
-		calculationTime: 3.0,
-		maxMoves:        10000,
-		plays:           map[string]int{},
-		wins:            map[string]int{},
-		stateToActions:  map[string][]*ActionState{},
+		CalculationTime: 3.0,
+		MaxMoves:        10000,
+		Workers:         1,
+		MaxNodes:        100000,
+		PlayoutDepth:    50,
+		Evaluator:       DefaultEvaluator,
 	}
 	return mcst
 }
 
-// An Action, plus the EndState it reaches upon being played.
-type ActionState struct {
-	Game   *Game
-	Action *Action
+// An mcstTree is the search tree of one worker.
+type mcstTree struct {
+	root    *mcstNode
+	nodes   int
+	rng     *Rng
+	rollout Strategy
+}
+
+// An mcstNode is an information set of the bot's player where someone has
+// more than one action to choose from.
+type mcstNode struct {
+	infoSet uint64
+	// count times a playout went through this node
+	visits int
+	// the actions that have been available at this node
+	edges []*mcstEdge
+}
+
+// An mcstEdge is an action from a node.
+type mcstEdge struct {
+	action uint64
+	// count times the action was taken in playouts
+	plays int
+	// count times it led to a win for the player who took it, where a playout
+	// that was cut off counts as the chance of a win
+	wins float64
+	// count times the action could have been taken, which is what the number
+	// of visits to the parent node is in a search tree without hidden information
+	available int
+	// The next node depends on what happens after the action, like which
+	// card gets drawn, so there is a child for each information set the
+	// playouts reached.
+	children map[uint64]*mcstNode
 }
 
 func (mb *McstBot) String() string {
 	return "McstBot"
 }
 
+func (mb *McstBot) Config() string {
+	budget := fmt.Sprintf("time=%gs", mb.CalculationTime)
+	if mb.Playouts > 0 {
+		budget = fmt.Sprintf("playouts=%d", mb.Playouts)
+	}
+	rollout := RandomRollout
+	if mb.Rollout != nil {
+		rollout = mb.Rollout
+	}
+	evaluator := "none"
+	if mb.Evaluator != nil {
+		evaluator = mb.Evaluator.String()
+	}
+	return fmt.Sprintf("C=%g %s workers=%d depth=%d rollout=%s evaluator=%s nodes=%d moves=%d",
+		mb.C, budget, mb.Workers, mb.PlayoutDepth, rollout, evaluator, mb.MaxNodes, mb.MaxMoves)
+}
+
 // Return the best play, after simulating possible plays and updating plays and wins stats.
 func (mb *McstBot) Action(g *Game) *Action {
+	if mb.Rng == nil {
+		mb.Rng = NewRng(botSeed(g))
+	}
 	legal := g.Actions(false)
 	if len(legal) == 1 {
 		return legal[0]
 	}
-	games := 0
+	if len(mb.trees) == 0 {
+		mb.trees = append(mb.trees, mb.newTree(mb.Rng))
+	}
+	for len(mb.trees) < mb.Workers {
+		// this doesn't use Rng, so the first worker searches the same with
+		// or without the others
+		seed := mix64(mb.Rng.State ^ uint64(len(mb.trees))*0x9e3779b97f4a7c15)
+		mb.trees = append(mb.trees, mb.newTree(&Rng{State: seed}))
+	}
+
+	me := g.PriorityId
+	infoSet := g.InformationSetHash(me)
+	maxNodes := 0
+	if mb.MaxNodes > 0 {
+		maxNodes = Max(2, mb.MaxNodes/len(mb.trees))
+	}
+	counts := make([]int, len(mb.trees))
 	start := time.Now()
-	for {
-		// print a spinner
-		mb.doPlayOut(g)
-		games++
-		if time.Since(start).Seconds() > mb.calculationTime {
-			break
-		}
+	var wg sync.WaitGroup
+	for i, tree := range mb.trees {
+		wg.Add(1)
+		go func(i int, tree *mcstTree) {
+			defer wg.Done()
+			tree.moveRoot(infoSet)
+			for {
+				mb.doPlayOut(tree, g.Determinize(me, tree.rng), me)
+				if maxNodes > 0 && tree.nodes > maxNodes {
+					tree.prune(maxNodes)
+				}
+				counts[i]++
+				if mb.Playouts > 0 {
+					if counts[i] >= mb.Playouts {
+						break
+					}
+				} else if time.Since(start).Seconds() > mb.CalculationTime {
+					break
+				}
+			}
+		}(i, tree)
+	}
+	wg.Wait()
+	games := 0
+	for _, count := range counts {
+		games += count
+	}
+	if !mb.Quiet {
+		fmt.Println("Simulated ", games, " games.")
 	}
-	fmt.Println("Simulated ", games, " games.")
 
-	actionStates := g.ActionStates()
-	bestActionState := actionStates[0]
+	bestAction := legal[0]
 	bestScore := 0.0
-	for index, as := range actionStates {
-		endStateStr := fmt.Sprintf("%s%d", string(as.Game.Serialize()), index)
-		if mb.plays[endStateStr] > 0 {
-			score := float64(mb.wins[endStateStr]) / float64(mb.plays[endStateStr])
+	for _, action := range legal {
+		plays, wins := 0, 0.0
+		for _, tree := range mb.trees {
+			if edge := tree.root.edge(action.hash()); edge != nil {
+				plays += edge.plays
+				wins += edge.wins
+			}
+		}
+		score := 0.0
+		if plays > 0 {
+			score = wins / float64(plays)
 			if score >= bestScore {
 				bestScore = score
-				bestActionState = as
+				bestAction = action
 			}
 		}
-		fmt.Printf("%s: %.2f (%d / %d)\n", as.Action.ShowTo(g.Priority()), float64(mb.wins[endStateStr])/float64(mb.plays[endStateStr]), mb.wins[endStateStr], mb.plays[endStateStr])
+		if !mb.Quiet {
+			fmt.Printf("%s: %.2f (%.1f / %d)\n", action.ShowTo(g.Priority()), score, wins, plays)
+		}
 	}
 
-	return bestActionState.Action
+	return bestAction
 }
 
-func (mb *McstBot) doPlayOut(g *Game) {
-	visitedStates := []string{}
-
-	cloneGame := CopyGame(g)
+// doPlayOut plays out a determinization of the game. Until it takes an action
+// that has never been played from its node, it goes down the tree, choosing
+// with UCB1. After that it plays by the Rollout policy, up to PlayoutDepth
+// actions.
+func (mb *McstBot) doPlayOut(tree *mcstTree, g *Game, me PlayerId) {
+	type step struct {
+		node   *mcstNode
+		edge   *mcstEdge
+		player PlayerId
+	}
+	path := []step{}
 
-	t := 0
-	bestActionState := &ActionState{}
-	unreachedState := false
-	for t = 0; t < mb.maxMoves; t++ {
-		currentStateStr := string(cloneGame.Serialize())
-		firstActionStr := fmt.Sprintf("%s%d", currentStateStr, 0)
-		actionStates := []*ActionState{}
-		actionIndex := 0
-		if mb.plays[firstActionStr] == 0 {
-			actionStates = cloneGame.ActionStates()
-			mb.stateToActions[currentStateStr] = actionStates
-			unreachedState = true
-		} else {
-			actionStates = mb.stateToActions[currentStateStr]
+	node := tree.root
+	var last *mcstEdge
+	expanded := false
+	rolloutMoves := 0
+	for t := 0; t < mb.MaxMoves && !g.IsOver(); t++ {
+		if mb.Evaluator != nil && mb.PlayoutDepth > 0 && rolloutMoves >= mb.PlayoutDepth {
+			break
 		}
-
-		if unreachedState {
-			actionIndex = rand.Intn(len(actionStates))
-			bestActionState = actionStates[actionIndex]
-		} else {
-			// decide best play based on prior simulations
-			logTotalPlays := 0.0
-			endStates := []string{}
-			for index, _ := range actionStates {
-				endStateStr := fmt.Sprintf("%s%d", currentStateStr, index)
-				logTotalPlays += float64(mb.plays[endStateStr])
-				endStates = append(endStates, endStateStr)
+		if expanded {
+			g.TakeAction(tree.rollout.Action(g))
+			rolloutMoves++
+			continue
+		}
+		actions := g.Actions(false)
+		index := 0
+		if len(actions) > 1 {
+			if node == nil {
+				node = tree.child(last, g.InformationSetHash(me))
 			}
-			logTotalPlays = math.Log(float64(logTotalPlays))
-
-			bestScore := 0.0
-			bestActionState = actionStates[0]
-			for index, as := range actionStates {
-				endStateStr := endStates[index]
-				winRatio := float64(mb.wins[endStateStr]) / float64(mb.plays[endStateStr])
-				logPlayRatio := float64(logTotalPlays) / float64(mb.plays[endStateStr])
-				score := winRatio + mb.C*math.Sqrt(logPlayRatio)
-				if score >= bestScore {
-					bestScore = score
-					bestActionState = as
-					actionIndex = index
+			edges := []*mcstEdge{}
+			unplayed := []int{}
+			for i, action := range actions {
+				edge := node.addEdge(action.hash())
+				edge.available++
+				edges = append(edges, edge)
+				if edge.plays == 0 {
+					unplayed = append(unplayed, i)
 				}
 			}
+			if len(unplayed) > 0 {
+				index = unplayed[tree.rng.Intn(len(unplayed))]
+				expanded = true
+			} else {
+				index = mb.bestByUCB1(edges)
+			}
+			path = append(path, step{node, edges[index], g.PriorityId})
+			last = edges[index]
+			node = nil
 		}
+		g.TakeAction(actions[index])
+	}
 
-		cloneGame.TakeAction(bestActionState.Action)
+	rewards := [2]float64{mb.reward(g, OnThePlay), mb.reward(g, OnTheDraw)}
+	for _, s := range path {
+		s.node.visits++
+		s.edge.plays++
+		s.edge.wins += rewards[s.player]
+	}
+}
 
-		if cloneGame.IsOver() {
-			break
+// reward is how much a playout that ended at g counts as a win for the player.
+func (mb *McstBot) reward(g *Game, id PlayerId) float64 {
+	if !g.IsOver() && mb.Evaluator != nil {
+		return mb.Evaluator.Evaluate(g, id)
+	}
+	if g.Winner() == id {
+		return 1
+	}
+	return 0
+}
+
+// bestByUCB1 returns the index of the edge whose action has the best upper
+// confidence bound on its win rate, for the player taking it.
+func (mb *McstBot) bestByUCB1(edges []*mcstEdge) int {
+	best := 0
+	bestScore := 0.0
+	for i, edge := range edges {
+		plays := float64(edge.plays)
+		winRatio := edge.wins / plays
+		score := winRatio + mb.C*math.Sqrt(math.Log(float64(edge.available))/plays)
+		if score >= bestScore {
+			bestScore = score
+			best = i
 		}
-		visitedStates = append(visitedStates, fmt.Sprintf("%s%d", currentStateStr, actionIndex))
 	}
+	return best
+}
 
-	for _, es := range visitedStates {
-		mb.plays[es] += 1
-		if cloneGame.Players[g.DefenderId()].Lost() {
-			mb.wins[es] += 1
+func (mb *McstBot) newTree(rng *Rng) *mcstTree {
+	rollout := mb.Rollout
+	if rollout == nil {
+		rollout = RandomRollout
+	}
+	return &mcstTree{rng: rng, rollout: rollout.NewStrategy(rng)}
+}
+
+// moveRoot makes the node for the information set the root of the tree.
+// If the tree doesn't have one, it starts over with a new root.
+func (t *mcstTree) moveRoot(infoSet uint64) {
+	root := t.root.find(infoSet)
+	if root == nil {
+		root = &mcstNode{infoSet: infoSet}
+	}
+	t.root = root
+	t.nodes = root.count()
+}
+
+// child returns the node an edge leads to for the information set, adding
+// it to the tree if it isn't there.
+func (t *mcstTree) child(e *mcstEdge, infoSet uint64) *mcstNode {
+	if e.children == nil {
+		e.children = map[uint64]*mcstNode{}
+	}
+	node, ok := e.children[infoSet]
+	if !ok {
+		node = &mcstNode{infoSet: infoSet}
+		e.children[infoSet] = node
+		t.nodes++
+	}
+	return node
+}
+
+// prune removes the branches that were never played and then the least
+// visited nodes, until the tree has at most half of maxNodes, so it has room
+// to grow before it needs pruning again. The root is never pruned.
+func (t *mcstTree) prune(maxNodes int) {
+	for threshold := 1; t.nodes > Max(1, maxNodes/2); threshold *= 2 {
+		t.nodes = t.root.prune(threshold)
+	}
+}
+
+// prune removes the unplayed edges below the node and the nodes with at most
+// threshold visits, and returns the number of nodes left.
+func (n *mcstNode) prune(threshold int) int {
+	count := 1
+	edges := n.edges[:0]
+	for _, e := range n.edges {
+		if e.plays == 0 {
+			continue
+		}
+		for infoSet, child := range e.children {
+			if child.visits <= threshold {
+				delete(e.children, infoSet)
+			} else {
+				count += child.prune(threshold)
+			}
 		}
+		edges = append(edges, e)
+	}
+	n.edges = edges
+	return count
+}
+
+// find returns the shallowest node for the information set, or nil.
+// It always finds the same node in the same tree, so that a search only
+// depends on its seeds.
+func (n *mcstNode) find(infoSet uint64) *mcstNode {
+	queue := []*mcstNode{}
+	if n != nil {
+		queue = append(queue, n)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node.infoSet == infoSet {
+			return node
+		}
+		for _, e := range node.edges {
+			queue = append(queue, e.sortedChildren()...)
+		}
+	}
+	return nil
+}
+
+// count returns the number of nodes in the tree below and including the node.
+func (n *mcstNode) count() int {
+	count := 1
+	for _, e := range n.edges {
+		for _, child := range e.children {
+			count += child.count()
+		}
+	}
+	return count
+}
+
+// edge returns the edge for the action, or nil if it has never been available.
+func (n *mcstNode) edge(action uint64) *mcstEdge {
+	for _, e := range n.edges {
+		if e.action == action {
+			return e
+		}
+	}
+	return nil
+}
+
+func (n *mcstNode) addEdge(action uint64) *mcstEdge {
+	e := n.edge(action)
+	if e == nil {
+		e = &mcstEdge{action: action}
+		n.edges = append(n.edges, e)
+	}
+	return e
+}
+
+func (e *mcstEdge) sortedChildren() []*mcstNode {
+	children := []*mcstNode{}
+	for _, child := range e.children {
+		children = append(children, child)
 	}
+	sort.Slice(children, func(i, j int) bool { return children[i].infoSet < children[j].infoSet })
+	return children
 }
@@ -0,0 +1,64 @@
+/*
+	Before the first turn, each player in turn order decides whether to keep
+	their opening hand, using the London mulligan rules:
+	https://mtg.gamepedia.com/Mulligan#London_mulligan
+
+	Taking a mulligan shuffles the hand back into the deck and draws a new
+	hand of 7. Once a player keeps, they put one card from their hand on the
+	bottom of their deck for each mulligan they took, one card at a time.
+*/
+
+package game
+
+import ()
+
+const OpeningHandSize = 7
+
+// Returns the possible actions during the Mulligan phase.
+func (p *Player) MulliganActions() []*Action {
+	answer := []*Action{}
+	if p.KeptHand {
+		cardNames := make(map[CardName]bool)
+		for _, name := range p.Hand {
+			if cardNames[name] {
+				continue
+			}
+			cardNames[name] = true
+			answer = append(answer, &Action{Type: PutOnBottom, Card: name.Card()})
+		}
+		return answer
+	}
+	answer = append(answer, &Action{Type: KeepHand})
+	if p.Mulligans < OpeningHandSize {
+		answer = append(answer, &Action{Type: TakeMulligan})
+	}
+	return answer
+}
+
+// TakeMulligan shuffles the player's hand into their deck and draws a new one.
+func (p *Player) TakeMulligan() {
+	for _, name := range p.Hand {
+		p.Deck.Add(1, name)
+	}
+	p.Hand = []CardName{}
+	p.Revealed = []CardName{}
+	p.handHash = 0
+	p.revealedHash = 0
+	p.Deck.Shuffle(p.game.Rng)
+	for i := 0; i < OpeningHandSize; i++ {
+		p.Draw()
+	}
+	p.Mulligans++
+}
+
+// PutOnBottom puts a card from the player's hand on the bottom of their deck.
+func (p *Player) PutOnBottom(name CardName) {
+	p.RemoveCardForActionFromHand(&Action{Card: name.Card()})
+	p.Deck.PutOnBottom(name)
+}
+
+// FinishedMulligan returns whether the player has kept a hand and put a card
+// on the bottom for each mulligan.
+func (p *Player) FinishedMulligan() bool {
+	return p.KeptHand && len(p.Hand) <= OpeningHandSize-p.Mulligans
+}
@@ -0,0 +1,500 @@
+/*
+	Actions hold pointers to cards and effects, which don't mean anything
+	outside of the game they came from. An ActionCode is an action written with
+	only names and ids, for replays, network clients and training data. It can
+	be encoded as JSON or as a short text notation, like
+
+		T3 M1 cast Rancor -> #12
+
+	which is Rancor cast on permanent 12 during the first main phase of turn 3.
+	The turn and phase are optional. Then comes a verb, the card if there is
+	one, the permanent that attacks, blocks or is activated or tapped as #id,
+	the target after "->" as #id, p0 or p1 for a player or s3 for a stack
+	object, and then any options.
+
+	An ActionCode only keeps what tells the legal actions apart, so decoding
+	one finds the action in Game.Actions with the same code.
+*/
+
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrStaleAction is the reason for an ActionError decoding an action that is
+// stamped with another turn or phase than the game's.
+var ErrStaleAction = errors.New("the action is for another turn or phase")
+
+type ActionCode struct {
+	// Turn and Phase say when the action was taken. Phase is empty if the code
+	// isn't stamped.
+	Turn  int    `json:"turn,omitempty"`
+	Phase string `json:"phase,omitempty"`
+
+	Verb string   `json:"action"`
+	Card CardName `json:"card,omitempty"`
+	// the permanent activated or tapped for mana
+	Source PermanentId `json:"source,omitempty"`
+	// the permanent attacking or blocking
+	With   PermanentId `json:"with,omitempty"`
+	Target string      `json:"target,omitempty"`
+
+	Alternate bool          `json:"alternate,omitempty"`
+	Kicker    bool          `json:"kicker,omitempty"`
+	Ninjitsu  bool          `json:"ninjitsu,omitempty"`
+	Phyrexian bool          `json:"phyrexian,omitempty"`
+	Selected  []PermanentId `json:"selected,omitempty"`
+	// the color of mana to tap for, as its symbol
+	Color       string        `json:"color,omitempty"`
+	SpellTarget StackObjectId `json:"etb,omitempty"`
+	// the permanent selected to pay for an ability, like a Forest for Quirion Ranger
+	CostPermanent PermanentId `json:"cost,omitempty"`
+
+	// The choice made by a MakeChoice action: the type of its effect, the
+	// cards in the order chosen, the cards scried to the top and bottom, and
+	// the land tapped to pay, like for Daze.
+	Effect string      `json:"effect,omitempty"`
+	Cards  []CardName  `json:"cards,omitempty"`
+	Top    []CardName  `json:"top,omitempty"`
+	Bottom []CardName  `json:"bottom,omitempty"`
+	Pay    PermanentId `json:"pay,omitempty"`
+}
+
+// The verb of each ActionType, indexed by it. Lands are played and other
+// cards are cast, but both are Play actions.
+var verbs = []string{
+	"pass", "cast", "activate", "attack", "block", "target-and-mana", "decide",
+	"decline", "etb", "choose", "pass-priority", "tap", "discard", "finish",
+	"keep", "mulligan", "bottom", "end-step-trigger",
+}
+
+// The short names of the phases, indexed by Phase.
+var phaseCodes = []string{
+	"MU", "UT", "UP", "DR", "M1", "BC", "DA", "DB", "CD", "EC", "M2", "ES", "CL",
+}
+
+var optionFlags = []string{"alternate", "kicker", "ninjitsu", "phyrexian"}
+
+// EncodeAction returns the code of an action, stamped with the game's turn
+// and phase.
+func (g *Game) EncodeAction(a *Action) ActionCode {
+	c := encodeAction(a)
+	c.Turn, c.Phase = g.Turn, phaseCodes[g.Phase]
+	return c
+}
+
+func encodeAction(a *Action) ActionCode {
+	c := ActionCode{
+		Verb:        verbs[a.Type],
+		Source:      a.Source,
+		With:        a.With,
+		Target:      targetCode(a.Target),
+		Alternate:   a.WithAlternate,
+		Kicker:      a.WithKicker,
+		Ninjitsu:    a.WithNinjitsu,
+		Phyrexian:   a.WithPhyrexian,
+		Selected:    a.Selected,
+		SpellTarget: a.EntersTheBattleFieldSpellTarget,
+	}
+	if a.Card != nil {
+		c.Card = a.Card.Name
+		if a.Type == Play && a.Card.IsLand() {
+			c.Verb = "play"
+		}
+	}
+	if a.Type == UseForMana {
+		c.Color = a.Color.Symbol()
+	}
+	if a.Cost != nil && a.Cost.Effect != nil {
+		c.CostPermanent = a.Cost.Effect.SelectedForCost
+	}
+	if e := a.AfterEffect; e != nil {
+		c.Effect = e.EffectType.String()
+		// a Delver of Secrets looking at an empty library sees NoCard
+		for _, name := range e.Cards {
+			if name != NoCard {
+				c.Cards = append(c.Cards, name)
+			}
+		}
+		if len(e.ScryCards) == 2 {
+			c.Top, c.Bottom = e.ScryCards[0], e.ScryCards[1]
+		}
+		c.Pay = e.SelectedForCost
+	}
+	return c
+}
+
+func targetCode(t Target) string {
+	switch t.Type {
+	case TargetPlayer:
+		return fmt.Sprintf("p%d", t.Player)
+	case TargetPermanent:
+		return fmt.Sprintf("#%d", t.Permanent)
+	case TargetStackObject:
+		return fmt.Sprintf("s%d", t.StackObject)
+	}
+	return ""
+}
+
+// FormatAction returns the text notation of an action, stamped with the
+// game's turn and phase.
+func (g *Game) FormatAction(a *Action) string {
+	return g.EncodeAction(a).String()
+}
+
+func (c ActionCode) String() string {
+	words := []string{}
+	if c.Phase != "" {
+		words = append(words, fmt.Sprintf("T%d", c.Turn), c.Phase)
+	}
+	words = append(words, c.Verb)
+	if c.Card != NoCard {
+		words = append(words, string(c.Card))
+	}
+	// the permanent that acts is written without a name
+	source, with := c.Source, c.With
+	if c.Verb == "activate" || c.Verb == "tap" {
+		words = append(words, idCode(source))
+		source = NoPermanentId
+	} else if with != NoPermanentId {
+		words = append(words, idCode(with))
+		with = NoPermanentId
+	}
+	if c.Target != "" {
+		words = append(words, "->", c.Target)
+	}
+	for i, flag := range []bool{c.Alternate, c.Kicker, c.Ninjitsu, c.Phyrexian} {
+		if flag {
+			words = append(words, optionFlags[i])
+		}
+	}
+	option := func(key string, value string) {
+		words = append(words, key+"="+value)
+	}
+	if source != NoPermanentId {
+		option("source", idCode(source))
+	}
+	if with != NoPermanentId {
+		option("with", idCode(with))
+	}
+	if len(c.Selected) > 0 {
+		ids := []string{}
+		for _, id := range c.Selected {
+			ids = append(ids, idCode(id))
+		}
+		option("selected", strings.Join(ids, ","))
+	}
+	if c.Color != "" {
+		option("color", c.Color)
+	}
+	if c.SpellTarget != NoStackObjectId {
+		option("etb", fmt.Sprintf("s%d", c.SpellTarget))
+	}
+	if c.CostPermanent != NoPermanentId {
+		option("cost", idCode(c.CostPermanent))
+	}
+	if c.Effect != "" {
+		option("effect", c.Effect)
+	}
+	for _, cards := range []struct {
+		key   string
+		names []CardName
+	}{{"cards", c.Cards}, {"top", c.Top}, {"bottom", c.Bottom}} {
+		if len(cards.names) > 0 {
+			option(cards.key, cardListCode(cards.names))
+		}
+	}
+	if c.Pay != NoPermanentId {
+		option("pay", idCode(c.Pay))
+	}
+	return strings.Join(words, " ")
+}
+
+func idCode(id PermanentId) string {
+	return fmt.Sprintf("#%d", id)
+}
+
+// Card names have spaces, so lists of them are bracketed and split by "|".
+func cardListCode(names []CardName) string {
+	s := make([]string, len(names))
+	for i, name := range names {
+		s[i] = string(name)
+	}
+	return "[" + strings.Join(s, "|") + "]"
+}
+
+// ParseActionCode reads the text notation of an action.
+func ParseActionCode(s string) (ActionCode, error) {
+	c := ActionCode{}
+	fail := func(format string, args ...interface{}) (ActionCode, error) {
+		return ActionCode{}, fmt.Errorf("bad action %q: %s", s, fmt.Sprintf(format, args...))
+	}
+	words, err := splitWords(s)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	if len(words) >= 2 && strings.HasPrefix(words[0], "T") && indexOfString(phaseCodes, words[1]) >= 0 {
+		turn, err := strconv.Atoi(words[0][1:])
+		if err != nil {
+			return fail("bad turn %q", words[0])
+		}
+		c.Turn, c.Phase = turn, words[1]
+		words = words[2:]
+	}
+	if len(words) == 0 {
+		return fail("no verb")
+	}
+	c.Verb = words[0]
+	if c.Verb != "play" && indexOfString(verbs, c.Verb) < 0 {
+		return fail("unknown verb %q", c.Verb)
+	}
+	words = words[1:]
+
+	// the card name is every word up to the first one that isn't part of it
+	name := []string{}
+	for len(words) > 0 && !isNotationWord(words[0]) {
+		name, words = append(name, words[0]), words[1:]
+	}
+	if len(name) > 0 {
+		if c.Card, err = parseCard(strings.Join(name, " ")); err != nil {
+			return fail("%v", err)
+		}
+	}
+
+	if len(words) > 0 && strings.HasPrefix(words[0], "#") {
+		id, err := parseId(words[0])
+		if err != nil {
+			return fail("%v", err)
+		}
+		if c.Verb == "activate" || c.Verb == "tap" {
+			c.Source = id
+		} else {
+			c.With = id
+		}
+		words = words[1:]
+	}
+	if len(words) > 0 && words[0] == "->" {
+		if len(words) < 2 {
+			return fail("no target after ->")
+		}
+		if _, err := parseTarget(words[1]); err != nil {
+			return fail("%v", err)
+		}
+		c.Target, words = words[1], words[2:]
+	}
+
+	for _, word := range words {
+		if err := c.parseOption(word); err != nil {
+			return fail("%v", err)
+		}
+	}
+	return c, nil
+}
+
+func (c *ActionCode) parseOption(word string) error {
+	switch word {
+	case "alternate":
+		c.Alternate = true
+		return nil
+	case "kicker":
+		c.Kicker = true
+		return nil
+	case "ninjitsu":
+		c.Ninjitsu = true
+		return nil
+	case "phyrexian":
+		c.Phyrexian = true
+		return nil
+	}
+	parts := strings.SplitN(word, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("unexpected %q", word)
+	}
+	key, value := parts[0], parts[1]
+	var err error
+	switch key {
+	case "source":
+		c.Source, err = parseId(value)
+	case "with":
+		c.With, err = parseId(value)
+	case "selected":
+		for _, s := range strings.Split(value, ",") {
+			id, idErr := parseId(s)
+			if idErr != nil {
+				return idErr
+			}
+			c.Selected = append(c.Selected, id)
+		}
+	case "color":
+		if len(value) != 1 {
+			return fmt.Errorf("bad color %q", value)
+		}
+		if _, ok := colorForSymbol(value[0]); !ok {
+			return fmt.Errorf("bad color %q", value)
+		}
+		c.Color = value
+	case "etb":
+		var t Target
+		t, err = parseTarget(value)
+		if err == nil && t.Type != TargetStackObject {
+			err = fmt.Errorf("expected a stack object, got %q", value)
+		}
+		c.SpellTarget = t.StackObject
+	case "cost":
+		c.CostPermanent, err = parseId(value)
+	case "effect":
+		c.Effect = value
+	case "cards":
+		c.Cards, err = parseCardList(value)
+	case "top":
+		c.Top, err = parseCardList(value)
+	case "bottom":
+		c.Bottom, err = parseCardList(value)
+	case "pay":
+		c.Pay, err = parseId(value)
+	default:
+		return fmt.Errorf("unknown option %q", key)
+	}
+	return err
+}
+
+// splitWords splits the notation at spaces, except inside brackets.
+func splitWords(s string) ([]string, error) {
+	words := []string{}
+	word := []rune{}
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unmatched ]")
+			}
+		case r == ' ' && depth == 0:
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+			continue
+		}
+		word = append(word, r)
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unmatched [")
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words, nil
+}
+
+// isNotationWord is whether a word is part of the notation after a card name.
+func isNotationWord(word string) bool {
+	return strings.HasPrefix(word, "#") || word == "->" || strings.Contains(word, "=") ||
+		indexOfString(optionFlags, word) >= 0
+}
+
+func indexOfString(list []string, s string) int {
+	for i, t := range list {
+		if t == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseCard reads a card name, in any case like a decklist.
+func parseCard(s string) (CardName, error) {
+	name, ok := cardNamesByKey[nameKey(s)]
+	if !ok {
+		return NoCard, fmt.Errorf("unknown card %q", s)
+	}
+	return name, nil
+}
+
+func parseId(s string) (PermanentId, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(s, "#"))
+	if err != nil || !strings.HasPrefix(s, "#") {
+		return NoPermanentId, fmt.Errorf("expected a permanent like #12, got %q", s)
+	}
+	return PermanentId(id), nil
+}
+
+func parseTarget(s string) (Target, error) {
+	if strings.HasPrefix(s, "#") {
+		id, err := parseId(s)
+		return PermanentTarget(id), err
+	}
+	if len(s) >= 2 {
+		n, err := strconv.Atoi(s[1:])
+		if err == nil && s[0] == 'p' && (n == int(OnThePlay) || n == int(OnTheDraw)) {
+			return PlayerTarget(PlayerId(n)), nil
+		}
+		if err == nil && s[0] == 's' {
+			return StackObjectTarget(StackObjectId(n)), nil
+		}
+	}
+	return NoTarget, fmt.Errorf("expected a target like #12, p1 or s3, got %q", s)
+}
+
+func parseCardList(s string) ([]CardName, error) {
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("expected a list of cards like [Island|Ponder], got %q", s)
+	}
+	names := []CardName{}
+	for _, part := range strings.Split(s[1:len(s)-1], "|") {
+		name, err := parseCard(part)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// DecodeAction returns the legal action with the code, or an *ActionError if
+// there is none or the code is stamped with another turn or phase.
+func (g *Game) DecodeAction(c ActionCode) (*Action, error) {
+	fail := func(err error) (*Action, error) {
+		return nil, &ActionError{Player: g.PriorityId, Turn: g.Turn, Phase: g.Phase, Err: err}
+	}
+	if g.IsOver() {
+		return fail(ErrGameOver)
+	}
+	if c.Phase != "" && (c.Turn != g.Turn || c.Phase != phaseCodes[g.Phase]) {
+		return fail(ErrStaleAction)
+	}
+	want := c.unstamped()
+	for _, a := range g.legalActions() {
+		if encodeAction(a).unstamped() == want {
+			return a, nil
+		}
+	}
+	return fail(ErrIllegalAction)
+}
+
+// unstamped returns the notation without the turn and phase, where lands can
+// be cast and other cards played.
+func (c ActionCode) unstamped() string {
+	c.Turn, c.Phase = 0, ""
+	if c.Verb == "play" {
+		c.Verb = "cast"
+	}
+	return c.String()
+}
+
+// ParseAction returns the legal action written in the text notation.
+func (g *Game) ParseAction(s string) (*Action, error) {
+	c, err := ParseActionCode(s)
+	if err != nil {
+		return nil, err
+	}
+	return g.DecodeAction(c)
+}
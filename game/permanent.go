@@ -36,6 +36,9 @@ type Permanent struct {
 	// Auras and equipment can have targets
 	Target PermanentId
 
+	// hashed is how much the permanent adds to its game's hash. See hash.go.
+	hashed uint64
+
 	// game should not be included when the permanent is serialized.
 	game *Game
 }
@@ -126,8 +129,8 @@ func (c *Permanent) AsciiImage(showBack bool) [CARD_HEIGHT][CARD_WIDTH]string {
 		if !c.IsLand() {
 			initialIndex := 2
 			ccRow := 1
-			ccString := fmt.Sprintf("%d", c.CastingCost.Colorless)
-			for x := initialIndex; x < len(ccString)+initialIndex; x++ {
+			ccString := c.CastingCost.String()
+			for x := initialIndex; x < Min(len(ccString)+initialIndex, cardWidth-1); x++ {
 				imageGrid[ccRow][x] = string(ccString[x-initialIndex])
 			}
 		}
@@ -209,26 +212,35 @@ func (c *Permanent) Trample() bool {
 func (c *Permanent) RespondToUntapPhase() {
 	if c.Name != NettleSentinel {
 		c.Tapped = false
+		c.rehash()
 	}
 }
 
 func (c *Permanent) RespondToSpell() {
 	if c.Name == NettleSentinel {
 		c.Tapped = false
+		c.rehash()
 	}
 }
 
+// ManaActions returns an action for each type of mana the permanent can make.
 func (c *Permanent) ManaActions() []*Action {
+	answer := []*Action{}
 	if c.IsLand() && !c.Tapped || c.SacrificesForMana {
-		return []*Action{&Action{Type: UseForMana, Source: c.Id}}
+		for _, color := range c.Produces {
+			answer = append(answer, &Action{Type: UseForMana, Source: c.Id, Color: color})
+		}
 	}
-	return []*Action{}
+	return answer
 }
 
-func (p *Permanent) UseForMana() {
+func (p *Permanent) UseForMana(color Color) {
 	owner := p.game.Player(p.Owner)
-	owner.AddMana(p.Colorless)
+	mana := Mana{}
+	mana[color] = 1
+	owner.AddMana(mana)
 	p.Tapped = true
+	p.rehash()
 	if p.SacrificesForMana {
 		owner.SendToGraveyard(p)
 	}
@@ -254,6 +266,7 @@ func (c *Permanent) HandleEnterTheBattlefield(id StackObjectId) {
 	owner := c.game.Player(c.Owner)
 	if c.Bloodthirst > 0 && owner.Opponent().DamageThisTurn > 0 {
 		c.Plus1Plus1Counters += c.Bloodthirst
+		c.rehash()
 	}
 
 	if id == NoStackObjectId {
@@ -263,13 +276,13 @@ func (c *Permanent) HandleEnterTheBattlefield(id StackObjectId) {
 	// TODO handle generically, this just handles ETB effects that target spells
 	if stackObject.EntersTheBattleFieldSpellTarget != NoStackObjectId {
 		so := &StackObject{
-			Type:        EntersTheBattlefieldEffect,
-			SpellTarget: stackObject.EntersTheBattleFieldSpellTarget,
-			Card:        stackObject.Card,
-			Player:      c.Owner,
+			Type:   EntersTheBattlefieldEffect,
+			Target: StackObjectTarget(stackObject.EntersTheBattleFieldSpellTarget),
+			Card:   stackObject.Card,
+			Player: c.Owner,
 		}
 		c.game.AddToStack(so)
-	} else if stackObject.Card.EntersTheBattlefieldEffect != nil {
+	} else if stackObject.Card.EntersTheBattlefieldEffect != nil && !stackObject.Card.HasEntersTheBattlefieldTargets() {
 		so := &StackObject{
 			Type:   EntersTheBattlefieldEffect,
 			Card:   stackObject.Card,
@@ -293,17 +306,16 @@ func (c *Permanent) DidDealDamage(damage int) {
 	}
 }
 
-func (c *Permanent) PayForActivatedAbility(cost *Cost, target PermanentId) {
+func (c *Permanent) PayForActivatedAbility(cost *Cost, target Target) {
 	if c.ActivatedAbility == nil {
 		panic("tried to activate a permanent without an ability")
 	}
 	c.ActivatedThisTurn = true
+	c.rehash()
 	selectedForCost := c.game.Permanent(cost.Effect.SelectedForCost)
 
 	if c.ActivatedAbility.Cost.Effect.EffectType == ReturnToHand {
-		owner := c.game.Player(selectedForCost.Owner)
-		owner.RemoveFromBoard(selectedForCost)
-		owner.Hand = append(owner.Hand, selectedForCost.Card.Name)
+		c.game.Player(selectedForCost.Owner).ReturnToHand(selectedForCost)
 	}
 }
 
@@ -312,6 +324,8 @@ func (c *Permanent) ActivateAbility(stackObject *StackObject) {
 		panic("tried to activate a permanent without an ability")
 	}
 	if c.ActivatedAbility.EffectType == Untap {
-		c.game.Permanent(stackObject.Target).Tapped = false
+		target := c.game.Permanent(stackObject.Target.Permanent)
+		target.Tapped = false
+		target.rehash()
 	}
 }
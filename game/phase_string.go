@@ -4,9 +4,9 @@ package game
 
 import "strconv"
 
-const _Phase_name = "UntapStepUpkeepDrawMain1DeclareAttackersDeclareBlockersCombatDamageMain2"
+const _Phase_name = "MulliganUntapStepUpkeepDrawMain1BeginningOfCombatDeclareAttackersDeclareBlockersCombatDamageEndOfCombatMain2EndStepCleanup"
 
-var _Phase_index = [...]uint8{0, 9, 15, 19, 24, 40, 55, 67, 72}
+var _Phase_index = [...]uint8{0, 8, 17, 23, 27, 32, 49, 65, 80, 92, 103, 108, 115, 122}
 
 func (i Phase) String() string {
 	if i < 0 || i >= Phase(len(_Phase_index)-1) {
@@ -3,18 +3,38 @@ package game
 import (
 	"fmt"
 	"log"
+	"strings"
 )
 
 type Player struct {
 	Board              []PermanentId
-	ColorlessManaPool  int
 	CreatureDied       bool
 	DamageThisTurn     int
 	Deck               *Deck
 	Hand               []CardName
 	Id                 PlayerId
+	KeptHand           bool
 	LandPlayedThisTurn int
 	Life               int
+	ManaPool           Mana
+	Mulligans          int
+
+	// Cards in the Graveyard and Exile are in the order they were put there,
+	// so the last one is on top.
+	Exile     []CardName
+	Graveyard []CardName
+
+	// Revealed holds the cards in Hand that the opponent has seen, like a
+	// creature that was returned to hand.
+	Revealed []CardName
+
+	// The sums of the hashes of the player's zones, which are kept up to date
+	// as they change. See hash.go.
+	handHash      uint64
+	revealedHash  uint64
+	graveyardHash uint64
+	exileHash     uint64
+	boardHash     uint64
 
 	// game should not be included when the player is serialized.
 	game *Game
@@ -23,13 +43,16 @@ type Player struct {
 // The caller should set game after construction.
 func NewPlayer(deck *Deck, id PlayerId) *Player {
 	p := &Player{
-		Life:  20,
-		Hand:  []CardName{},
-		Id:    id,
-		Board: []PermanentId{},
-		Deck:  deck,
-	}
-	for i := 0; i < 7; i++ {
+		Life:      20,
+		Hand:      []CardName{},
+		Id:        id,
+		Board:     []PermanentId{},
+		Deck:      deck,
+		Exile:     []CardName{},
+		Graveyard: []CardName{},
+		Revealed:  []CardName{},
+	}
+	for i := 0; i < OpeningHandSize; i++ {
 		p.Draw()
 	}
 	return p
@@ -42,21 +65,28 @@ func (p *Player) Draw() {
 		return
 	}
 	p.Hand = append(p.Hand, card)
+	p.handHash += feature(handFeature, nameHash(card))
 }
 
 func (p *Player) GetBoard() []*Permanent {
 	return p.game.GetPermanents(p.Board)
 }
 
-func (p *Player) AvailableMana() int {
-	answer := 0
+// manaSources returns the mana the player could spend: first the mana in their
+// pool, then their untapped lands.
+func (p *Player) manaSources() []manaSource {
+	sources := []manaSource{}
+	for c, amount := range p.ManaPool {
+		for i := 0; i < amount; i++ {
+			sources = append(sources, manaSource{colors: []Color{Color(c)}})
+		}
+	}
 	for _, card := range p.GetBoard() {
 		if card.IsLand() && !card.Tapped {
-			answer += card.Colorless
+			sources = append(sources, manaSource{colors: card.Produces, land: card.Id})
 		}
 	}
-	answer += p.ColorlessManaPool
-	return answer
+	return sources
 }
 
 func (p *Player) Untap() {
@@ -79,11 +109,12 @@ func (p *Player) EndCombat() {
 		card.Attacking = false
 		card.Blocking = NoPermanentId
 		card.DamageOrder = []PermanentId{}
+		card.rehash()
 	}
 }
 
 func (p *Player) EndPhase() {
-	p.ColorlessManaPool = 0
+	p.ManaPool = Mana{}
 }
 
 func (p *Player) EndTurn() {
@@ -91,6 +122,7 @@ func (p *Player) EndTurn() {
 		perm.Damage = 0
 		perm.TemporaryEffects = []*Effect{}
 		perm.ActivatedThisTurn = false
+		perm.rehash()
 	}
 	p.LandPlayedThisTurn = 0
 	p.DamageThisTurn = 0
@@ -109,7 +141,9 @@ func (p *Player) Creatures() []*Permanent {
 }
 
 func (p *Player) SendToGraveyard(perm *Permanent) {
-	removedPerm := p.RemoveFromBoard(perm)
+	owner := p.game.Player(perm.Owner)
+	removedPerm := owner.RemoveFromBoard(perm)
+	owner.MoveToGraveyard(removedPerm.FrontFace())
 	if removedPerm.EntersGraveyardEffect != nil {
 		p.ResolveEffect(removedPerm.EntersGraveyardEffect, removedPerm)
 	}
@@ -119,6 +153,7 @@ func (p *Player) SendToGraveyard(perm *Permanent) {
 	}
 
 	removedPerm.TemporaryEffects = []*Effect{}
+	removedPerm.rehash()
 	for _, aura := range removedPerm.GetAuras() {
 		if aura.EnchantedPermanentDiesEffect != nil {
 			p.ResolveEffect(aura.EnchantedPermanentDiesEffect, aura)
@@ -134,12 +169,99 @@ func (p *Player) RemoveFromBoard(perm *Permanent) *Permanent {
 			newBoard = append(newBoard, id)
 		}
 	}
+	if len(newBoard) < len(p.Board) {
+		p.boardHash -= feature(boardFeature, uint64(perm.Id))
+	}
 	p.Board = newBoard
-	if perm.IsTransformed {
-		return p.game.newPermanent(Cards[perm.TransformInto], perm.Owner, NoStackObjectId, true)
-	} else {
-		return perm
+	return perm
+}
+
+// IsOnBoard returns whether the player has the permanent on the battlefield.
+func (p *Player) IsOnBoard(perm *Permanent) bool {
+	for _, id := range p.Board {
+		if id == perm.Id {
+			return true
+		}
+	}
+	return false
+}
+
+// ReturnToHand puts a permanent into its owner's hand, either from the
+// battlefield or, like Rancor, from the graveyard it was just put into.
+func (p *Player) ReturnToHand(perm *Permanent) {
+	owner := p.game.Player(perm.Owner)
+	if owner.IsOnBoard(perm) {
+		owner.RemoveFromBoard(perm)
+	} else if !owner.RemoveFromGraveyard(perm.FrontFace()) {
+		return
+	}
+	if !perm.Token {
+		owner.Hand = append(owner.Hand, perm.FrontFace())
+		owner.Revealed = append(owner.Revealed, perm.FrontFace())
+		owner.handHash += feature(handFeature, nameHash(perm.FrontFace()))
+		owner.revealedHash += feature(revealedFeature, nameHash(perm.FrontFace()))
+	}
+}
+
+// MoveToGraveyard puts a card on top of the player's graveyard.
+// Tokens cease to exist instead.
+func (p *Player) MoveToGraveyard(name CardName) {
+	if name.Card().Token {
+		return
+	}
+	p.graveyardHash += cardFeature(graveyardFeature, len(p.Graveyard), name)
+	p.Graveyard = append(p.Graveyard, name)
+}
+
+// RemoveFromGraveyard removes the topmost copy of a card from the player's
+// graveyard. It returns false if there is no such card in the graveyard.
+func (p *Player) RemoveFromGraveyard(name CardName) bool {
+	for i := len(p.Graveyard) - 1; i >= 0; i-- {
+		if p.Graveyard[i] == name {
+			p.Graveyard = append(p.Graveyard[:i:i], p.Graveyard[i+1:]...)
+			p.rehashGraveyard()
+			return true
+		}
+	}
+	return false
+}
+
+// MoveToExile puts a card on top of the player's exile zone.
+// Tokens cease to exist instead.
+func (p *Player) MoveToExile(name CardName) {
+	if name.Card().Token {
+		return
+	}
+	p.exileHash += cardFeature(exileFeature, len(p.Exile), name)
+	p.Exile = append(p.Exile, name)
+}
+
+// TopOfGraveyard returns the card on top of the player's graveyard, or NoCard
+// if it is empty.
+func (p *Player) TopOfGraveyard() CardName {
+	if len(p.Graveyard) == 0 {
+		return NoCard
 	}
+	return p.Graveyard[len(p.Graveyard)-1]
+}
+
+// GetGraveyard returns the cards in the player's graveyard, from bottom to top.
+func (p *Player) GetGraveyard() []*Card {
+	return cardsFromNames(p.Graveyard)
+}
+
+// GetExile returns the cards the player owns in exile, in the order they
+// were exiled.
+func (p *Player) GetExile() []*Card {
+	return cardsFromNames(p.Exile)
+}
+
+func cardsFromNames(names []CardName) []*Card {
+	answer := []*Card{}
+	for _, name := range names {
+		answer = append(answer, name.Card())
+	}
+	return answer
 }
 
 // Returns possible actions when we can activate cards on the board.
@@ -172,14 +294,14 @@ func (p *Player) ActivatedAbilityActions(allowSorcerySpeed bool, forHuman bool)
 			if effect.Selector.Type == Creature { // TODO lands etc
 				for _, c := range p.Creatures() {
 					for _, land := range landsForCost {
-						costEffect := effect.Cost.Effect
+						costEffect := *effect.Cost.Effect
 						costEffect.SelectedForCost = land.Id
 						answer = append(answer,
 							&Action{
 								Type:   Activate,
-								Cost:   &Cost{Effect: costEffect},
+								Cost:   &Cost{Effect: &costEffect},
 								Source: perm.Id,
-								Target: c.Id,
+								Target: PermanentTarget(c.Id),
 							})
 					}
 				}
@@ -208,9 +330,9 @@ func (p *Player) PlayActions(allowSorcerySpeed bool, forHuman bool) []*Action {
 			}
 			answer = p.appendActionsIfNonInstant(answer, card, forHuman)
 		}
-		targetsSpellOrCreature := card.HasCreatureTargets() || card.HasSpellTargets()
-		hasLegalTarget := p.HasLegalPermanentTarget(card) || p.HasLegalSpellTarget(card)
-		if card.IsInstant() && (hasLegalTarget || !targetsSpellOrCreature) {
+		targetsSomething := card.HasCreatureTargets() || card.HasSpellTargets() || card.HasPlayerTargets()
+		hasLegalTarget := p.HasLegalPermanentTarget(card) || p.HasLegalSpellTarget(card) || card.HasPlayerTargets()
+		if card.IsInstant() && (hasLegalTarget || !targetsSomething) {
 			if forHuman {
 				answer = p.appendHumanChoiceIfCanPayCostAndHasTarget(answer, card)
 			} else {
@@ -222,7 +344,7 @@ func (p *Player) PlayActions(allowSorcerySpeed bool, forHuman bool) []*Action {
 			answer = p.appendActionsIfNonInstant(answer, card, forHuman)
 		}
 
-		if card.Ninjitsu != nil && p.game.Phase == CombatDamage {
+		if card.Ninjitsu != nil && p.game.ninjitsuAllowed() {
 			answer = p.appendActionsIfNonInstant(answer, card, forHuman)
 		}
 
@@ -237,7 +359,7 @@ func (p *Player) HasLegalPermanentTarget(c *Card) bool {
 		return false
 	}
 	for _, creature := range p.game.Creatures() {
-		if p.IsLegalTarget(c, creature) {
+		if p.IsLegalTarget(c, PermanentTarget(creature.Id)) {
 			return true
 		}
 	}
@@ -258,13 +380,13 @@ func (p *Player) HasLegalSpellTarget(c *Card) bool {
 }
 
 func (p *Player) appendHumanChoiceIfCanPayCostAndHasTarget(answer []*Action, card *Card) []*Action {
-	targetsSpellOrCreature := card.HasCreatureTargets() || card.HasSpellTargets()
-	hasLegalTarget := p.HasLegalPermanentTarget(card) || p.HasLegalSpellTarget(card)
-	if !hasLegalTarget && targetsSpellOrCreature {
+	targetsSomething := card.HasCreatureTargets() || card.HasSpellTargets() || card.HasPlayerTargets()
+	hasLegalTarget := p.HasLegalPermanentTarget(card) || p.HasLegalSpellTarget(card) || card.HasPlayerTargets()
+	if !hasLegalTarget && targetsSomething {
 		return answer
 	}
 	if p.CanPayCost(card.CastingCost) ||
-		(card.Kicker != nil && p.CanPayCost(card.Kicker.Cost)) ||
+		(card.Kicker != nil && p.CanPayCost(card.KickedCost())) ||
 		(card.PhyrexianCastingCost != nil && p.CanPayCost(card.PhyrexianCastingCost)) ||
 		(card.AlternateCastingCost != nil && p.CanPayCost(card.AlternateCastingCost)) {
 		answer = append(answer, &Action{
@@ -288,21 +410,22 @@ func removePermanent(attackers []*Permanent, attacker *Permanent) []*Permanent {
 // Appends actions to answer for an instant card.
 func (p *Player) appendActionsForInstant(answer []*Action, card *Card) []*Action {
 	if p.CanPayCost(card.CastingCost) {
-		// TODO - add player targets - this assumes all instants target creatures or spells
 		if card.HasSpellTargets() {
 			for _, spellAction := range p.game.GetStack() {
 				if spellAction.Type == Play {
 					answer = append(answer, &Action{
-						Type:        Play,
-						Card:        card,
-						SpellTarget: spellAction.Id,
+						Type:   Play,
+						Card:   card,
+						Target: StackObjectTarget(spellAction.Id),
 					})
 				}
 			}
+		} else if card.HasPlayerTargets() {
+			answer = p.appendActionsForPlayerTargets(answer, card)
 		} else {
 
 			for _, targetCreature := range p.game.Creatures() {
-				if p.IsLegalTarget(card, targetCreature) {
+				if p.IsLegalTarget(card, PermanentTarget(targetCreature.Id)) {
 					selectableLandCount := selectableLandCount(card)
 					if selectableLandCount > 0 { // snap
 						for i := 1; i <= len(p.game.Lands())-1; i++ {
@@ -316,7 +439,7 @@ func (p *Player) appendActionsForInstant(answer []*Action, card *Card) []*Action
 									Type:     Play,
 									Card:     card,
 									Selected: selected,
-									Target:   targetCreature.Id,
+									Target:   PermanentTarget(targetCreature.Id),
 								})
 							}
 						}
@@ -324,20 +447,20 @@ func (p *Player) appendActionsForInstant(answer []*Action, card *Card) []*Action
 						answer = append(answer, &Action{
 							Type:   Play,
 							Card:   card,
-							Target: targetCreature.Id,
+							Target: PermanentTarget(targetCreature.Id),
 						})
 					}
 				}
 			}
 		}
 	}
-	if card.Kicker != nil && p.CanPayCost(card.Kicker.Cost) {
+	if card.Kicker != nil && p.CanPayCost(card.KickedCost()) {
 		for _, target := range p.game.Creatures() {
-			if p.IsLegalTarget(card, target) {
+			if p.IsLegalTarget(card, PermanentTarget(target.Id)) {
 				answer = append(answer, &Action{
 					Type:       Play,
 					Card:       card,
-					Target:     target.Id,
+					Target:     PermanentTarget(target.Id),
 					WithKicker: true,
 				})
 			}
@@ -346,11 +469,11 @@ func (p *Player) appendActionsForInstant(answer []*Action, card *Card) []*Action
 
 	if card.PhyrexianCastingCost != nil && p.CanPayCost(card.PhyrexianCastingCost) {
 		for _, target := range p.game.Creatures() {
-			if p.IsLegalTarget(card, target) {
+			if p.IsLegalTarget(card, PermanentTarget(target.Id)) {
 				answer = append(answer, &Action{
 					Type:          Play,
 					Card:          card,
-					Target:        target.Id,
+					Target:        PermanentTarget(target.Id),
 					WithPhyrexian: true,
 				})
 			}
@@ -382,6 +505,21 @@ func (p *Player) appendActionsForInstant(answer []*Action, card *Card) []*Action
 	return answer
 }
 
+// Appends an action to cast card targeting each player it can target.
+func (p *Player) appendActionsForPlayerTargets(answer []*Action, card *Card) []*Action {
+	for _, player := range p.game.Players {
+		target := PlayerTarget(player.Id)
+		if p.IsLegalTarget(card, target) {
+			answer = append(answer, &Action{
+				Type:   Play,
+				Card:   card,
+				Target: target,
+			})
+		}
+	}
+	return answer
+}
+
 // Appends new Actions to answer for selectedLands, used for Gush and Daze
 func (p *Player) addActionsForSelectedLands(card *Card, answer []*Action, selectedLands []PermanentId) []*Action {
 	if card.HasSpellTargets() { // daze
@@ -391,7 +529,7 @@ func (p *Player) addActionsForSelectedLands(card *Card, answer []*Action, select
 					Type:          Play,
 					Card:          card,
 					Selected:      selectedLands,
-					SpellTarget:   spellAction.Id,
+					Target:        StackObjectTarget(spellAction.Id),
 					WithAlternate: true,
 				})
 			}
@@ -500,8 +638,10 @@ func (p *Player) appendActionsIfNonInstant(answer []*Action, card *Card, forHuma
 			if card.IsCreature() {
 				if card.HasEntersTheBattlefieldTargets() {
 					if card.EntersTheBattlefieldEffect.Selector.Type == Spell {
+						hasSpellTarget := false
 						for _, spellTarget := range p.game.GetStack() {
 							if spellTarget.Type == Play {
+								hasSpellTarget = true
 								answer = append(answer, &Action{
 									Type: Play,
 									Card: card,
@@ -509,6 +649,13 @@ func (p *Player) appendActionsIfNonInstant(answer []*Action, card *Card, forHuma
 								})
 							}
 						}
+						// with no spell to target, like at end of turn, the effect does nothing
+						if !hasSpellTarget {
+							answer = append(answer, &Action{
+								Type: Play,
+								Card: card,
+							})
+						}
 					} else {
 						panic("unhandled EntersTheBattlefieldEffect.Selector.Type")
 					}
@@ -523,9 +670,11 @@ func (p *Player) appendActionsIfNonInstant(answer []*Action, card *Card, forHuma
 					answer = append(answer, &Action{
 						Type:   Play,
 						Card:   card,
-						Target: target.Id,
+						Target: PermanentTarget(target.Id),
 					})
 				}
+			} else if card.IsSorcery() && card.HasPlayerTargets() {
+				answer = p.appendActionsForPlayerTargets(answer, card)
 			} else if card.IsSorcery() {
 				answer = append(answer, &Action{
 					Type: Play,
@@ -538,7 +687,7 @@ func (p *Player) appendActionsIfNonInstant(answer []*Action, card *Card, forHuma
 			answer = append(answer, &Action{Type: Play, Card: card, WithPhyrexian: true})
 		}
 
-		if card.Ninjitsu != nil && p.CanPayCost(card.Ninjitsu) && p.game.Phase == CombatDamage {
+		if card.Ninjitsu != nil && p.CanPayCost(card.Ninjitsu) && p.game.ninjitsuAllowed() {
 			for _, a := range p.unblockedAtackers() {
 				answer = append(answer, &Action{
 					Type:         Play,
@@ -588,6 +737,30 @@ func (p *Player) PassAction() *Action {
 	return &Action{Type: Pass}
 }
 
+// Returns the possible actions of type 'Discard', one for each different card
+// in hand, when the player has more than the maximum hand size.
+func (p *Player) DiscardActions() []*Action {
+	answer := []*Action{}
+	if len(p.Hand) <= MaxHandSize {
+		return answer
+	}
+	cardNames := make(map[CardName]bool)
+	for _, name := range p.Hand {
+		if cardNames[name] {
+			continue
+		}
+		cardNames[name] = true
+		answer = append(answer, &Action{Type: Discard, Card: name.Card()})
+	}
+	return answer
+}
+
+// Discard puts a card from the player's hand into their graveyard.
+func (p *Player) Discard(name CardName) {
+	p.RemoveCardForActionFromHand(&Action{Card: name.Card()})
+	p.MoveToGraveyard(name)
+}
+
 // Returns the possible actions of type 'Attack'.
 func (p *Player) AttackActions() []*Action {
 	if p.game.Phase != DeclareAttackers {
@@ -617,7 +790,7 @@ func (p *Player) BlockActions() []*Action {
 				if perm.CanBlock(attacker) {
 					answer = append(answer, &Action{
 						Type:   Block,
-						Target: attacker.Id,
+						Target: PermanentTarget(attacker.Id),
 						With:   perm.Id,
 					})
 				}
@@ -644,13 +817,17 @@ func (p *Player) RemoveCardForActionFromHand(action *Action) {
 		panic("cannot continue")
 	}
 	p.Hand = newHand
+	p.handHash -= feature(handFeature, nameHash(card.Name))
+	if i := indexOf(p.Revealed, card.Name); i >= 0 {
+		p.Revealed = append(p.Revealed[:i:i], p.Revealed[i+1:]...)
+		p.revealedHash -= feature(revealedFeature, nameHash(card.Name))
+	}
 }
 
 func (p *Player) PayCostsAndPutSpellOnStack(action *Action) {
 
 	so := &StackObject{
 		Type:                            action.Type,
-		SpellTarget:                     action.SpellTarget,
 		Card:                            action.Card,
 		Player:                          p.Id,
 		Selected:                        action.Selected,
@@ -668,15 +845,17 @@ func (p *Player) PayCostsAndPutSpellOnStack(action *Action) {
 	card := action.Card
 	if !card.IsLand() {
 		if action.WithKicker {
-			p.PayCost(card.Kicker.Cost) // TODO use UpdatedEffectForAction when cardpool expands
+			p.PayCost(card.KickedCost()) // TODO use UpdatedEffectForAction when cardpool expands
 		} else if action.WithAlternate {
-			card.AlternateCastingCost.Effect = UpdatedEffectForStackObject(so, card.AlternateCastingCost.Effect)
-			p.PayCost(card.AlternateCastingCost)
+			cost := *card.AlternateCastingCost
+			cost.Effect = UpdatedEffectForStackObject(so, cost.Effect)
+			p.PayCost(&cost)
 		} else if action.WithPhyrexian {
 			p.PayCost(card.PhyrexianCastingCost) // TODO use UpdatedEffectForAction when cardpool expands
 		} else if action.WithNinjitsu {
-			card.Ninjitsu.Effect = UpdatedEffectForStackObject(so, card.Ninjitsu.Effect)
-			p.PayCost(card.Ninjitsu)
+			cost := *card.Ninjitsu
+			cost.Effect = UpdatedEffectForStackObject(so, cost.Effect)
+			p.PayCost(&cost)
 		} else {
 			p.PayCost(card.CastingCost)
 		}
@@ -712,40 +891,47 @@ func (p *Player) ResolveSpell(stackObject *StackObject) {
 
 	if card.IsSpell() {
 		p.CastSpell(card, stackObject.Target, stackObject)
-		// TODO put spells (instants and sorceries) in graveyard (or exile)
+		p.MoveToGraveyard(card.Name)
 	} else {
 		// Non-spell (instant/sorcery) cards turn into permanents
 		perm := p.game.newPermanent(card, p.Id, stackObject.Id, true)
 		if stackObject.WithNinjitsu {
 			perm.Attacking = true
 			perm.Tapped = true
+			perm.rehash()
 		}
 
 		if card.IsEnchantCreature() {
-			target := p.game.Permanent(stackObject.Target)
+			target := p.game.Permanent(stackObject.Target.Permanent)
 			target.Auras = append(target.Auras, perm.Id)
+			target.rehash()
 		}
 	}
 }
 
-func (p *Player) CastSpell(c *Card, targetId PermanentId, stackObject *StackObject) {
+func (p *Player) CastSpell(c *Card, target Target, stackObject *StackObject) {
 	if c.AddsTemporaryEffect {
-		target := p.game.Permanent(targetId)
-		for _, e := range c.Effects {
-			target.TemporaryEffects = append(target.TemporaryEffects, UpdatedEffectForStackObject(stackObject, e))
+		if target.Type == TargetPermanent {
+			perm := p.game.Permanent(target.Permanent)
+			for _, e := range c.Effects {
+				perm.TemporaryEffects = append(perm.TemporaryEffects, UpdatedEffectForStackObject(stackObject, e))
+			}
+			perm.rehash()
 		}
 	} else if c.Effects != nil {
 		for _, e := range c.Effects {
 			p.ResolveEffect(UpdatedEffectForStackObject(stackObject, e), nil)
-			if targetId != NoPermanentId {
-				target := p.game.Permanent(targetId)
-				target.Plus1Plus1Counters += e.Plus1Plus1Counters // can be and often is 0 here
+			if target.Type == TargetPermanent {
+				perm := p.game.Permanent(target.Permanent)
+				perm.Plus1Plus1Counters += e.Plus1Plus1Counters // can be and often is 0 here
+				perm.rehash()
 			}
 		}
 	}
-	if c.Morbid != nil && (p.CreatureDied || p.Opponent().CreatureDied) && targetId != NoPermanentId {
-		target := p.game.Permanent(targetId)
-		target.Plus1Plus1Counters += c.Morbid.Plus1Plus1Counters
+	if c.Morbid != nil && (p.CreatureDied || p.Opponent().CreatureDied) && target.Type == TargetPermanent {
+		perm := p.game.Permanent(target.Permanent)
+		perm.Plus1Plus1Counters += c.Morbid.Plus1Plus1Counters
+		perm.rehash()
 	}
 }
 
@@ -753,8 +939,8 @@ func (p *Player) ResolveActivatedAbility(stackObject *StackObject) {
 	p.game.Permanent(stackObject.Source).ActivateAbility(stackObject)
 }
 
-func (p *Player) AddMana(colorless int) {
-	p.ColorlessManaPool += colorless
+func (p *Player) AddMana(mana Mana) {
+	p.ManaPool = p.ManaPool.Plus(mana)
 }
 
 func (p *Player) Print(position int, hideCards bool, gameWidth int) {
@@ -763,7 +949,9 @@ func (p *Player) Print(position int, hideCards bool, gameWidth int) {
 		PrintRowOfPermanents(p.Lands(), gameWidth)
 		PrintRowOfCards(p.Hand, gameWidth)
 		fmt.Printf("\n%s", p.AvatarString(position, gameWidth))
+		fmt.Printf("\n%s", p.ZonesString(gameWidth))
 	} else {
+		fmt.Printf("\n%s", p.ZonesString(gameWidth))
 		fmt.Printf("\n%s\n", p.AvatarString(position, gameWidth))
 		PrintRowOfCards(p.Hand, gameWidth)
 		PrintRowOfPermanents(p.Lands(), gameWidth)
@@ -796,10 +984,31 @@ func (p *Player) AvatarString(position int, gameWidth int) string {
 	for x := 0; x < (gameWidth-len(playerString))/2; x++ {
 		playerString += " "
 	}
-	playerString += fmt.Sprintf("<Life: %d> Player %d <Mana: %d>", p.Life, position, p.ColorlessManaPool)
+	playerString += fmt.Sprintf("<Life: %d> Player %d <Mana: %s>", p.Life, position, p.ManaPool)
 	return playerString
 }
 
+// ZonesString describes the player's graveyard and exile, topmost card first.
+func (p *Player) ZonesString(gameWidth int) string {
+	zones := fmt.Sprintf("Graveyard: %s | Exile: %s", zoneString(p.Graveyard), zoneString(p.Exile))
+	zonesString := ""
+	for x := 0; x < (gameWidth-len(zones))/2; x++ {
+		zonesString += " "
+	}
+	return zonesString + zones
+}
+
+func zoneString(zone []CardName) string {
+	if len(zone) == 0 {
+		return "empty"
+	}
+	names := []string{}
+	for i := len(zone) - 1; i >= 0; i-- {
+		names = append(names, string(zone[i]))
+	}
+	return strings.Join(names, ", ")
+}
+
 func PrintRowOfCards(cards []CardName, gameWidth int) {
 	perms := []*Permanent{}
 	for _, name := range cards {
@@ -841,9 +1050,22 @@ func (p *Player) GetCreature(name CardName) *Permanent {
 func (p *Player) DealDamage(damage int) {
 	p.Life -= damage
 	p.DamageThisTurn += damage
+	p.game.logDamage(p.Id, nil, damage)
 }
 
-func (p *Player) IsLegalTarget(c *Card, perm *Permanent) bool {
+// IsLegalTarget returns whether the player can target target with the card c.
+func (p *Player) IsLegalTarget(c *Card, target Target) bool {
+	if target.Type == TargetPlayer {
+		return c.HasPlayerTargets()
+	}
+	if target.Type == TargetStackObject {
+		so, ok := p.game.StackObjects[target.StackObject]
+		return ok && so.Type == Play
+	}
+	if target.Type != TargetPermanent {
+		return false
+	}
+	perm := p.game.Permanent(target.Permanent)
 	if p.Id != perm.Owner && perm.Hexproof {
 		return false
 	}
@@ -880,9 +1102,11 @@ func (p *Player) ResolveEffect(e *Effect, perm *Permanent) {
 					controlCount++
 				}
 			}
-			so := p.game.StackObject(e.SpellTarget)
-			if so != nil && e.SpellTarget != NoStackObjectId && controlCount < so.Card.CastingCost.Colorless {
-				return
+			if e.Target.Type == TargetStackObject {
+				so := p.game.StackObject(e.Target.StackObject)
+				if so != nil && controlCount < so.Card.CastingCost.ConvertedManaCost() {
+					return
+				}
 			}
 		}
 		if e.Condition.ControlAnother == NoCard &&
@@ -894,41 +1118,56 @@ func (p *Player) ResolveEffect(e *Effect, perm *Permanent) {
 		p.game.newPermanent(e.Summon.Card(), p.Id, NoStackObjectId, true)
 	} else if e.EffectType == ReturnToHand {
 		// target is nil for rancor, or any effect of a permanent on itself
-		if e.Target == NoPermanentId && perm == nil {
+		if e.Target.Type != TargetPermanent && perm == nil {
 			for _, selected := range e.Selected {
-				selectedPerm := p.game.Permanent(selected)
-				removedPerm := p.RemoveFromBoard(selectedPerm)
-				p.Hand = append(p.Hand, removedPerm.Card.Name)
+				p.ReturnToHand(p.game.Permanent(selected))
 			}
 		} else {
 			effectedPermanent := perm
-			if e.Target != NoPermanentId {
-				effectedPermanent = p.game.Permanent(e.Target)
+			if e.Target.Type == TargetPermanent {
+				effectedPermanent = p.game.Permanent(e.Target.Permanent)
 			}
-			owner := p.game.Player(effectedPermanent.Owner)
-			removedPerm := owner.RemoveFromBoard(effectedPermanent)
-			owner.Hand = append(owner.Hand, removedPerm.Card.Name)
+			p.ReturnToHand(effectedPermanent)
 		}
 	} else if e.EffectType == Untap {
 		if e.Selector == nil { // nettle sentinel, or any effect of a permanent on itself
 			perm.Tapped = false
+			perm.rehash()
 		} else {
 			for _, s := range e.Selected {
-				p.game.Permanent(s).Tapped = false
+				untapped := p.game.Permanent(s)
+				untapped.Tapped = false
+				untapped.rehash()
 			}
 		}
 	} else if e.EffectType == AddMana {
-		p.ColorlessManaPool += e.Colorless
+		p.AddMana(e.Mana)
 	} else if e.EffectType == DrawCard {
 		drawCount := 1
 		if e.Selector != nil {
 			drawCount = Max(drawCount, e.Selector.Count)
 		}
+		drawer := p
+		if e.Target.Type == TargetPlayer {
+			drawer = p.game.Player(e.Target.Player)
+		}
 		for i := 0; i < drawCount; i++ {
-			p.Draw()
+			drawer.Draw()
+		}
+	} else if e.EffectType == DealDamage {
+		if e.Target.Type == TargetPlayer {
+			p.game.Player(e.Target.Player).DealDamage(e.Damage)
+		} else if e.Target.Type == TargetPermanent {
+			target := p.game.Permanent(e.Target.Permanent)
+			target.Damage += e.Damage
+			target.rehash()
+			p.game.logDamage(target.Owner, target, e.Damage)
+			if target.IsCreature() && target.Damage >= target.Toughness() {
+				p.game.Player(target.Owner).SendToGraveyard(target)
+			}
 		}
 	} else if e.EffectType == Countermagic {
-		p.game.RemoveSpellFromStack(e.SpellTarget)
+		p.game.RemoveSpellFromStack(e.Target.StackObject)
 	} else if e.EffectType == ManaSink ||
 		e.EffectType == TopScryDraw ||
 		e.EffectType == ScryDraw ||
@@ -938,43 +1177,50 @@ func (p *Player) ResolveEffect(e *Effect, perm *Permanent) {
 			as the next action
 		*/
 		if perm != nil {
-			e.Selected = []PermanentId{perm.Id}
+			// e can be the effect of a card, which is shared between games
+			choice := *e
+			choice.Selected = []PermanentId{perm.Id}
+			e = &choice
 		}
 		p.game.ChoiceEffect = e
 		if e.EffectType == ManaSink {
 			p.game.PriorityId = p.game.Priority().Opponent().Id
 		}
 	} else if e.EffectType == SpendMana {
-		p.ColorlessManaPool -= e.Cost.Colorless
+		p.SpendMana(e.Cost)
 	} else if e.EffectType == TapLand {
-		p.game.Permanent(e.SelectedForCost).Tapped = true
-	} else if e.EffectType == ReturnCardsToTopDraw || e.EffectType == ShuffleDraw {
+		land := p.game.Permanent(e.SelectedForCost)
+		land.Tapped = true
+		land.rehash()
+	} else if e.EffectType == ReturnCardsToTopDraw {
+		// the cards were only looked at, so they are still on top
+		p.Deck.RemoveFromTop(len(e.Cards))
 		for i := len(e.Cards) - 1; i >= 0; i-- {
 			p.Deck.AddToTop(1, e.Cards[i])
 		}
-		if e.EffectType == ShuffleDraw {
-			p.Deck.Shuffle()
-		}
+		p.Deck.KnownTop = len(e.Cards)
+		p.Draw()
+	} else if e.EffectType == ShuffleDraw {
+		p.Deck.Shuffle(p.game.Rng)
 		p.Draw()
 	} else if e.EffectType == ReturnScryCardsDraw {
-		for index, cardList := range e.ScryCards {
-			for _, card := range cardList {
-				if index == 0 {
-					p.Deck.AddToTop(1, card)
-				} else {
-					p.Deck.Add(1, card)
-				}
-			}
+		top := e.ScryCards[0]
+		bottom := e.ScryCards[1]
+		p.Deck.RemoveFromTop(len(top) + len(bottom))
+		for i := len(top) - 1; i >= 0; i-- {
+			p.Deck.AddToTop(1, top[i])
 		}
+		for _, card := range bottom {
+			p.Deck.PutOnBottom(card)
+		}
+		p.Deck.KnownTop = len(top)
 		p.Draw()
 	} else if e.EffectType == DelverScryReveal ||
 		e.EffectType == DelverScryNoReveal {
-		// return card
-		for _, card := range e.Cards {
-			p.Deck.AddToTop(1, card)
-		}
+		// the top card was only looked at, so it stays where it is
+		p.Deck.KnownTop = Max(p.Deck.KnownTop, Min(1, len(p.Deck.Cards)))
 		if e.EffectType == DelverScryReveal {
-			// TODO reveal when that matters
+			p.Deck.RevealedTop = Max(p.Deck.RevealedTop, Min(1, len(p.Deck.Cards)))
 
 			// flip
 			delver := p.game.Permanent(e.Selected[0])
@@ -985,6 +1231,7 @@ func (p *Player) ResolveEffect(e *Effect, perm *Permanent) {
 			for _, a := range auras {
 				perm.Auras = append(perm.Auras, a)
 			}
+			perm.rehash()
 		}
 	} else {
 		panic("tried to resolve unknown effect")
@@ -993,7 +1240,7 @@ func (p *Player) ResolveEffect(e *Effect, perm *Permanent) {
 
 // Returns whether the player has the resources (life, mana, etc) to pay Cost.
 func (p *Player) CanPayCost(c *Cost) bool {
-	if p.AvailableMana() < c.Colorless {
+	if findManaPayment(c.manaSymbols(), p.manaSources()) == nil {
 		return false
 	}
 	if c.Effect == nil {
@@ -1018,7 +1265,7 @@ func (p *Player) CanPayCost(c *Cost) bool {
 func (p *Player) PayCost(c *Cost) bool {
 
 	// regular mana costs
-	p.SpendMana(c.Colorless)
+	p.SpendMana(c)
 
 	// Phyrexian costs
 	p.Life -= c.Life
@@ -1030,30 +1277,27 @@ func (p *Player) PayCost(c *Cost) bool {
 	return false
 }
 
-// Automatically spends the given amount of mana.
-// Panics if we do not have that much.
-func (p *Player) SpendMana(amount int) {
-	if p.ColorlessManaPool >= amount {
-		p.ColorlessManaPool -= amount
-		return
-	} else {
-		amount -= p.ColorlessManaPool
-		p.ColorlessManaPool = 0
-
-	}
-	for _, card := range p.GetBoard() {
-		if amount == 0 {
-			return
-		}
-		if card.IsLand() && !card.Tapped {
-			card.Tapped = true
-			amount -= 1
-		}
-	}
-	if amount > 0 {
+// Automatically spends the mana for a Cost, using mana from the pool before
+// tapping lands.
+// Panics if there is no way to pay it.
+func (p *Player) SpendMana(c *Cost) {
+	sources := p.manaSources()
+	payment := findManaPayment(c.manaSymbols(), sources)
+	if payment == nil {
 		p.game.Print()
 		panic("could not spend mana")
 	}
+	for _, s := range payment {
+		source := sources[s]
+		if source.land != NoPermanentId {
+			land := p.game.Permanent(source.land)
+			land.Tapped = true
+			land.rehash()
+		} else {
+			// mana in the pool has a single color
+			p.ManaPool[source.colors[0]]--
+		}
+	}
 }
 
 // Returns possible Actions for choices like Daze, Ponder, and Scry.
@@ -1083,17 +1327,17 @@ func (p *Player) waysToChoose(effect *Effect) []*Action {
 			})
 		}
 	}
-	if p.ColorlessManaPool > 0 {
+	if p.ManaPool.Total() > 0 {
 		answer = append(answer, &Action{
 			Type:                 MakeChoice,
-			AfterEffect:          &Effect{EffectType: SpendMana, Cost: &Cost{Colorless: 1}},
+			AfterEffect:          &Effect{EffectType: SpendMana, Cost: &Cost{Generic: 1}},
 			ShouldSwitchPriority: true,
 		})
 	}
 
 	answer = append(answer, &Action{
 		Type:                 MakeChoice,
-		AfterEffect:          &Effect{EffectType: Countermagic, SpellTarget: p.game.ChoiceEffect.SpellTarget},
+		AfterEffect:          &Effect{EffectType: Countermagic, Target: p.game.ChoiceEffect.Target},
 		ShouldSwitchPriority: true,
 	})
 	return answer
@@ -1105,10 +1349,7 @@ func (p *Player) waysToChoose(effect *Effect) []*Action {
 */
 func (p *Player) waysToArrange(effect *Effect) []*Action {
 
-	cards := []CardName{}
-	for i := 0; i < Min(effect.Selector.Count, len(p.Deck.Cards)); i++ {
-		cards = append(cards, p.Deck.Draw())
-	}
+	cards := p.Deck.Peek(effect.Selector.Count)
 
 	perms := permutations(cards)
 
@@ -1136,7 +1377,7 @@ func permutations(arr []CardName) [][]CardName {
 	res := [][]CardName{}
 
 	helper = func(arr []CardName, n int) {
-		if n == 1 {
+		if n <= 1 {
 			tmp := make([]CardName, len(arr))
 			copy(tmp, arr)
 			res = append(res, tmp)
@@ -1164,10 +1405,7 @@ func permutations(arr []CardName) [][]CardName {
 */
 func (p *Player) waysToScry(effect *Effect) []*Action {
 
-	cards := []CardName{}
-	for i := 0; i < Min(effect.Selector.Count, len(p.Deck.Cards)); i++ {
-		cards = append(cards, p.Deck.Draw())
-	}
+	cards := p.Deck.Peek(effect.Selector.Count)
 
 	perms := permutations(cards)
 	slicedPerms := [][][]CardName{}
@@ -1196,36 +1434,31 @@ func (p *Player) waysToScry(effect *Effect) []*Action {
 }
 
 /*
-	If not an instant/sorcery, draws and undraws the card and returns empty list.
-	Otherwise returns actions to reveal or not
-
+	Looks at the top card of the deck without drawing it.
+	If it is not an instant/sorcery, the only action is to not reveal it.
+	Otherwise returns actions to reveal or not.
 */
 func (p *Player) waysToDelverScry(effect *Effect) []*Action {
 
-	card := p.Deck.Draw().Card()
+	name := NoCard
+	if top := p.Deck.Peek(1); len(top) > 0 {
+		name = top[0]
+	}
+	card := name.Card()
 
-	if card == nil {
+	if card == nil || !card.IsSpell() {
 		return []*Action{
 			&Action{
 				Type: MakeChoice,
 				AfterEffect: &Effect{
 					EffectType: DelverScryNoReveal,
-					Cards:      []CardName{NoCard},
+					Cards:      []CardName{name},
 					Selected:   effect.Selected,
 				},
 			},
 		}
 	}
 
-	if !(card.IsSpell()) {
-		p.Deck.AddToTop(1, card.Name)
-		return []*Action{
-			&Action{
-				Type: Pass,
-			},
-		}
-	}
-
 	return []*Action{
 		&Action{
 			Type: MakeChoice,
@@ -0,0 +1,149 @@
+/*
+	The registry has a name for each Strategy and Deck, so that commands can
+	choose them from flags and scripts.
+*/
+
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BotConfig holds the parameters a registered Strategy is made with. Bots
+// ignore the parameters that don't apply to them.
+type BotConfig struct {
+	Seed int64
+	// for McstBot: the exploration constant, the seconds to think for each
+	// action, and if positive, the playouts per worker instead of a time limit.
+	// McstBot's defaults are used for the ones that are zero.
+	C               float64
+	CalculationTime float64
+	Playouts        int
+	Workers         int
+	// Quiet stops bots from printing what they think about each action.
+	Quiet bool
+	// for ExternalBot, the program to run, with its arguments split by spaces
+	Command string
+}
+
+// DefaultBotConfig is the configuration of bots made with their New function.
+func DefaultBotConfig(seed int64) BotConfig {
+	mb := NewMcstBot()
+	return BotConfig{
+		Seed:            seed,
+		C:               mb.C,
+		CalculationTime: mb.CalculationTime,
+		Workers:         mb.Workers,
+	}
+}
+
+var strategies = map[string]func(config BotConfig) Strategy{
+	"attack": func(config BotConfig) Strategy {
+		return &AttackBot{}
+	},
+	"external": func(config BotConfig) Strategy {
+		b := NewExternalBot(config.Command)
+		b.Quiet = config.Quiet
+		if len(b.Command) == 0 {
+			// the bot can only play its Fallback, and Err says why
+			b.Err = fmt.Errorf("the external strategy needs a command to run")
+		}
+		return b
+	},
+	"greedy": func(config BotConfig) Strategy {
+		return NewGreedyBot(config.Seed)
+	},
+	"human": func(config BotConfig) Strategy {
+		return &Human{}
+	},
+	"mcst": func(config BotConfig) Strategy {
+		mb := NewMcstBot()
+		mb.Rng = NewRng(config.Seed)
+		mb.Playouts = config.Playouts
+		mb.Quiet = config.Quiet
+		if config.C > 0 {
+			mb.C = config.C
+		}
+		if config.CalculationTime > 0 {
+			mb.CalculationTime = config.CalculationTime
+		}
+		if config.Workers > 0 {
+			mb.Workers = config.Workers
+		}
+		return mb
+	},
+	"montecarlo": func(config BotConfig) Strategy {
+		return &SimpleMonteCarloBot{Rng: NewRng(config.Seed)}
+	},
+	"random": func(config BotConfig) Strategy {
+		return NewRandomBot(config.Seed)
+	},
+}
+
+var decks = map[string]func() *Deck{
+	"delver": MonoBlueDelver,
+	"stompy": Stompy,
+}
+
+// RegisterStrategy makes a Strategy available by name. It panics if the name
+// is already taken.
+func RegisterStrategy(name string, newStrategy func(config BotConfig) Strategy) {
+	if _, ok := strategies[name]; ok {
+		panic("there is already a strategy named " + name)
+	}
+	strategies[name] = newStrategy
+}
+
+// RegisterDeck makes a Deck available by name. It panics if the name is
+// already taken.
+func RegisterDeck(name string, newDeck func() *Deck) {
+	if _, ok := decks[name]; ok {
+		panic("there is already a deck named " + name)
+	}
+	decks[name] = newDeck
+}
+
+// NewStrategy returns a new Strategy of the registered name.
+func NewStrategy(name string, config BotConfig) (Strategy, error) {
+	newStrategy, ok := strategies[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q, expected one of %s", name,
+			strings.Join(StrategyNames(), ", "))
+	}
+	return newStrategy(config), nil
+}
+
+// LoadDeck returns a new copy of the registered deck with the given name, or
+// if there isn't one, the deck in the decklist file at that path.
+func LoadDeck(nameOrPath string) (*Deck, error) {
+	if newDeck, ok := decks[strings.ToLower(nameOrPath)]; ok {
+		return newDeck(), nil
+	}
+	if !strings.ContainsAny(nameOrPath, "./") {
+		return nil, fmt.Errorf("unknown deck %q, expected one of %s or a decklist file",
+			nameOrPath, strings.Join(DeckNames(), ", "))
+	}
+	return ReadDecklist(nameOrPath)
+}
+
+// StrategyNames returns the names of the registered strategies in order.
+func StrategyNames() []string {
+	names := []string{}
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DeckNames returns the names of the registered decks in order.
+func DeckNames() []string {
+	names := []string{}
+	for name := range decks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,98 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// ReplayVersion is the version of the replay file format. Replays of other
+// versions can't be loaded.
+const ReplayVersion = 2
+
+// A Replay is what it takes to play a game again exactly: the seed, the decks
+// and the actions taken.
+type Replay struct {
+	Version int
+	Seed    int64
+	// The cards of each deck before NewGame shuffled them, on the play first.
+	Decks [2][]CardName
+	// The strategies that played, on the play first, for reference.
+	Strategies [2]string
+	Steps      []ReplayStep
+}
+
+// A ReplayStep is one action.
+type ReplayStep struct {
+	// The action in the notation of FormatAction.
+	Action string
+	// How the player saw the action, so the file is easier to read.
+	Shown string
+}
+
+// LoggedGame makes a game like NewGame, that keeps an EventLog with a Replay.
+func LoggedGame(deckToPlay *Deck, deckToDraw *Deck, seed int64) *Game {
+	replay := &Replay{
+		Version: ReplayVersion,
+		Seed:    seed,
+		Decks:   [2][]CardName{copyCardNames(deckToPlay.Cards), copyCardNames(deckToDraw.Cards)},
+	}
+	g := NewGame(deckToPlay, deckToDraw, seed)
+	g.Log = &EventLog{Replay: replay}
+	return g
+}
+
+// step returns the ReplayStep of an action that is about to be taken.
+func (r *Replay) step(g *Game, action *Action) ReplayStep {
+	return ReplayStep{Action: g.FormatAction(action), Shown: action.ShowTo(g.Priority())}
+}
+
+// Play plays the replay. It returns the game before each step and after the
+// last one, and the events of each step.
+func (r *Replay) Play() ([]*Game, [][]*Event, error) {
+	deckToPlay := &Deck{Cards: copyCardNames(r.Decks[0])}
+	deckToDraw := &Deck{Cards: copyCardNames(r.Decks[1])}
+	g := NewGame(deckToPlay, deckToDraw, r.Seed)
+	g.Log = &EventLog{}
+	games := []*Game{g.Clone()}
+	events := [][]*Event{}
+	for i, step := range r.Steps {
+		if g.IsOver() {
+			return games, events, fmt.Errorf("the game is over after %d of %d actions", i, len(r.Steps))
+		}
+		action, err := g.ParseAction(step.Action)
+		if err != nil {
+			return games, events, fmt.Errorf("action %d: %v", i+1, err)
+		}
+		n := len(g.Log.Events)
+		g.TakeAction(action)
+		events = append(events, g.Log.Since(n))
+		games = append(games, g.Clone())
+	}
+	return games, events, nil
+}
+
+// Save writes the replay to a file as JSON.
+func (r *Replay) Save(path string) error {
+	bytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, bytes, 0644)
+}
+
+// LoadReplay reads a replay saved with Save.
+func LoadReplay(path string) (*Replay, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replay{}
+	if err := json.Unmarshal(bytes, r); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if r.Version != ReplayVersion {
+		return nil, fmt.Errorf("%s: replay version %d, expected %d", path, r.Version, ReplayVersion)
+	}
+	return r, nil
+}
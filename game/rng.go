@@ -0,0 +1,50 @@
+/*
+	Rng is the source of randomness for a Game, like shuffles. Games with the
+	same seed and the same actions play out the same way.
+
+	Its whole state is the exported State, so it is serialized with the Game,
+	and a copy of a game continues with the same random numbers as the
+	original.
+
+	Bots don't draw from the game's Rng. If they did, how many numbers a bot
+	drew would change the shuffles that come later, so a game could only be
+	replayed with the same bots and not from its seed and actions alone.
+	Instead each bot has its own Rng, which is seeded from the game's seed if
+	it isn't given one, so a game between bots is still reproducible from the
+	game's seed.
+*/
+
+package game
+
+// An Rng is a splitmix64 generator.
+// http://xoshiro.di.unimi.it/splitmix64.c
+type Rng struct {
+	State uint64
+}
+
+func NewRng(seed int64) *Rng {
+	return &Rng{State: uint64(seed)}
+}
+
+func (r *Rng) Uint64() uint64 {
+	r.State += 0x9e3779b97f4a7c15
+	return mix64(r.State)
+}
+
+// Int63 returns a non-negative random int64, which is handy as a seed.
+func (r *Rng) Int63() int64 {
+	return int64(r.Uint64() >> 1)
+}
+
+// Intn returns a random int in [0, n). It panics if n <= 0.
+func (r *Rng) Intn(n int) int {
+	if n <= 0 {
+		panic("Intn needs a positive n")
+	}
+	return int(r.Uint64() % uint64(n))
+}
+
+// Float64 returns a random float64 in [0, 1).
+func (r *Rng) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
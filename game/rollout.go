@@ -0,0 +1,78 @@
+package game
+
+import (
+	"fmt"
+)
+
+// A RolloutPolicy makes the Strategy that chooses the actions of a McstBot
+// worker's playouts once they leave the search tree.
+type RolloutPolicy struct {
+	Name string
+	// NewStrategy is given the worker's Rng, since workers run at the same
+	// time and can't share a Strategy that keeps its own.
+	NewStrategy func(rng *Rng) Strategy
+}
+
+func (p *RolloutPolicy) String() string {
+	return p.Name
+}
+
+// RandomRollout plays uniformly random actions.
+var RandomRollout = &RolloutPolicy{
+	Name: "random",
+	NewStrategy: func(rng *Rng) Strategy {
+		return &RandomBot{Rng: rng}
+	},
+}
+
+// AttackRollout plays like an AttackBot.
+var AttackRollout = &RolloutPolicy{
+	Name: "attack",
+	NewStrategy: func(rng *Rng) Strategy {
+		return &AttackBot{}
+	},
+}
+
+// GreedyRollout plays like a GreedyBot with the DefaultEvaluator.
+var GreedyRollout = &RolloutPolicy{
+	Name: "greedy",
+	NewStrategy: func(rng *Rng) Strategy {
+		return &GreedyBot{Evaluator: DefaultEvaluator, Rng: rng}
+	},
+}
+
+// EpsilonGreedyRollout plays a random action with probability epsilon, and
+// otherwise the action of the greedy policy.
+func EpsilonGreedyRollout(epsilon float64, greedy *RolloutPolicy) *RolloutPolicy {
+	return &RolloutPolicy{
+		Name: fmt.Sprintf("epsilon-greedy(%g,%s)", epsilon, greedy),
+		NewStrategy: func(rng *Rng) Strategy {
+			return &EpsilonGreedyBot{Greedy: greedy.NewStrategy(rng), Epsilon: epsilon, Rng: rng}
+		},
+	}
+}
+
+// An EpsilonGreedyBot takes a random action with probability Epsilon, and
+// otherwise lets Greedy choose. A little randomness keeps playouts from
+// always going the same way.
+type EpsilonGreedyBot struct {
+	Greedy  Strategy
+	Epsilon float64
+	// Rng is seeded from the first game the bot plays if it is not set.
+	Rng *Rng
+}
+
+func (b *EpsilonGreedyBot) String() string {
+	return "EpsilonGreedyBot"
+}
+
+func (b *EpsilonGreedyBot) Action(g *Game) *Action {
+	if b.Rng == nil {
+		b.Rng = NewRng(botSeed(g))
+	}
+	if b.Rng.Float64() < b.Epsilon {
+		actions := g.Actions(false)
+		return actions[b.Rng.Intn(len(actions))]
+	}
+	return b.Greedy.Action(g)
+}
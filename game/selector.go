@@ -51,6 +51,7 @@ type Type int
 // Some card types appear only on cards used in variants such as Planechase and Archenemy.
 // Phenomenon, Vanguards, Schemes
 // the Type Spell denotes all other Types except Land
+// the Type AnyPlayer is for effects that target a player rather than a card
 const (
 	Artifact Type = iota
 	Creature
@@ -61,6 +62,7 @@ const (
 	Sorcery
 	Tribal
 	Spell
+	AnyPlayer
 )
 
 //go:generate stringer -type=AttackStatus
@@ -18,8 +18,7 @@ type StackObject struct {
 	Player                          PlayerId
 	Selected                        []PermanentId
 	Source                          PermanentId
-	SpellTarget                     StackObjectId
-	Target                          PermanentId // a target that is a Permanent (players not yet handled)
+	Target                          Target
 	WithNinjitsu                    bool
 }
 
@@ -27,6 +26,9 @@ func (s *StackObject) String() string {
 	if s.Type == EntersTheBattlefieldEffect {
 		return fmt.Sprintf("%s Enters the Battlefield effect", s.Card)
 	}
+	if s.Type == BeginningOfEndStepEffect {
+		return fmt.Sprintf("%s Beginning of End Step effect", s.Card)
+	}
 	if s.Card != nil {
 		return fmt.Sprintf("resolve %s", s.Card)
 	}
@@ -2,8 +2,6 @@ package game
 
 import (
 	"fmt"
-	"github.com/jinzhu/copier"
-	"math/rand"
 )
 
 // The only thing a strategy has to do is to decide an action based on the current
@@ -16,17 +14,45 @@ type Strategy interface {
 	Action(g *Game) *Action
 }
 
-type RandomBot struct{}
+// A Configurable strategy has parameters that change how well it plays, so
+// that ratings can tell its configurations apart.
+type Configurable interface {
+	// Config describes the parameters, like "C=3 playouts=200".
+	Config() string
+}
+
+// A RandomBot takes a random action each time it has priority.
+// Like the other bots, it keeps its own Rng instead of using the game's, as
+// rng.go explains.
+type RandomBot struct {
+	// Rng is seeded from the first game the bot plays if it is not set.
+	Rng *Rng
+}
+
+func NewRandomBot(seed int64) *RandomBot {
+	return &RandomBot{Rng: NewRng(seed)}
+}
 
 func (b *RandomBot) String() string {
 	return "RandomBot"
 }
 
 func (b *RandomBot) Action(g *Game) *Action {
+	if b.Rng == nil {
+		b.Rng = NewRng(botSeed(g))
+	}
 	actions := g.Actions(false)
-	return actions[rand.Int()%len(actions)]
+	return actions[b.Rng.Intn(len(actions))]
+}
+
+// botSeed is a seed for a bot that was not given one, derived from the game's
+// seed and the bot's player so that the two bots of a game play differently.
+func botSeed(g *Game) int64 {
+	return g.Seed ^ int64(g.PriorityId+1)*0x5851f42d4c957f2d
 }
 
+// PlayGame plays out the game and returns the id of the winner, or NoPlayerId
+// if the game is a draw.
 func PlayGame(g *Game, strategy0 Strategy, strategy1 Strategy, printResult bool) PlayerId {
 	for !g.IsOver() {
 		strategy := []Strategy{strategy0, strategy1}[g.PriorityIndex()]
@@ -34,32 +60,33 @@ func PlayGame(g *Game, strategy0 Strategy, strategy1 Strategy, printResult bool)
 		g.TakeAction(action)
 	}
 
-	if g.Players[0].Lost() {
-		if g.Players[1].Lost() {
-			if printResult {
-				fmt.Println("The game is a draw.")
-			}
-		} else {
-			if printResult {
-				fmt.Printf("%s wins.\n", strategy1)
-			}
-		}
-		return g.DefenderId()
-	} else {
-		if printResult {
+	winner := g.Winner()
+	if printResult {
+		switch winner {
+		case NoPlayerId:
+			fmt.Println("The game is a draw.")
+		case g.Players[0].Id:
 			fmt.Printf("%s wins.\n", strategy0)
+		default:
+			fmt.Printf("%s wins.\n", strategy1)
 		}
-		return g.AttackerId()
 	}
+	return winner
 }
 
-type SimpleMonteCarloBot struct{}
+type SimpleMonteCarloBot struct {
+	// Rng seeds the playouts, and is seeded from the game if it is not set.
+	Rng *Rng
+}
 
 func (b *SimpleMonteCarloBot) String() string {
 	return "SimpleMonteCarloBot"
 }
 
 func (b *SimpleMonteCarloBot) Action(g *Game) *Action {
+	if b.Rng == nil {
+		b.Rng = NewRng(botSeed(g))
+	}
 	actions := g.Actions(false)
 
 	scores := []int{}
@@ -86,12 +113,12 @@ func (b *SimpleMonteCarloBot) calcWinRate(g *Game, moveIndex int, iterations int
 	wins := 0
 	losses := 0
 	for i := 0; i < iterations; i++ {
-		cloneGame := Game{}
-		copier.Copy(&cloneGame, &g)
+		cloneGame := g.Clone()
+		cloneGame.Rng = NewRng(b.Rng.Int63())
 
 		move := cloneGame.Actions(false)[moveIndex]
 		cloneGame.TakeAction(move)
-		winner := PlayGame(&cloneGame, &RandomBot{}, &RandomBot{}, false)
+		winner := PlayGame(cloneGame, NewRandomBot(b.Rng.Int63()), NewRandomBot(b.Rng.Int63()), false)
 		if winner == g.PriorityId {
 			wins += 1
 		} else {
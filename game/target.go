@@ -0,0 +1,89 @@
+/*
+	A Target is what a spell or ability targets: a player, a permanent, or a
+	spell or ability on the stack.
+
+	Only the field for the Target's Type is set, so Targets can be compared
+	with ==.
+*/
+
+package game
+
+import (
+	"fmt"
+)
+
+//go:generate stringer -type=TargetType
+type TargetType int
+
+const (
+	NoTargetType TargetType = iota
+	TargetPlayer
+	TargetPermanent
+	TargetStackObject
+)
+
+type Target struct {
+	Type        TargetType
+	Player      PlayerId
+	Permanent   PermanentId
+	StackObject StackObjectId
+}
+
+// NoTarget is the Target of spells and abilities that don't target anything.
+var NoTarget = Target{}
+
+func PlayerTarget(id PlayerId) Target {
+	return Target{Type: TargetPlayer, Player: id}
+}
+
+func PermanentTarget(id PermanentId) Target {
+	return Target{Type: TargetPermanent, Permanent: id}
+}
+
+func StackObjectTarget(id StackObjectId) Target {
+	return Target{Type: TargetStackObject, StackObject: id}
+}
+
+func (t Target) String() string {
+	switch t.Type {
+	case TargetPlayer:
+		return fmt.Sprintf("player %d", t.Player)
+	case TargetPermanent:
+		return fmt.Sprintf("permanent %d", t.Permanent)
+	case TargetStackObject:
+		return fmt.Sprintf("stack object %d", t.StackObject)
+	}
+	return "no target"
+}
+
+// ShowTo describes the target from the point of view of the player p.
+// For debugging and logging. Don't use this in the critical path.
+func (t Target) ShowTo(p *Player) string {
+	switch t.Type {
+	case TargetPlayer:
+		if t.Player == p.Id {
+			return "you"
+		}
+		return "your opponent"
+	case TargetPermanent:
+		perm := p.game.Permanent(t.Permanent)
+		return fmt.Sprintf("%s %s", pronoun(p, perm.Owner), perm)
+	case TargetStackObject:
+		so := p.game.StackObject(t.StackObject)
+		if so == nil {
+			return "a spell that left the stack"
+		}
+		if so.Card == nil {
+			return fmt.Sprintf("%s ability of %s", pronoun(p, so.Player), p.game.Permanent(so.Source))
+		}
+		return fmt.Sprintf("%s %s", pronoun(p, so.Player), so.Card)
+	}
+	return "nothing"
+}
+
+func pronoun(p *Player, id PlayerId) string {
+	if id == p.Id {
+		return "your"
+	}
+	return "their"
+}
@@ -0,0 +1,103 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The reasons an action can't be taken. An ActionError wraps one of them, so
+// they can be checked for with errors.Is.
+var (
+	ErrNoAction      = errors.New("there is no action")
+	ErrGameOver      = errors.New("the game is over")
+	ErrIllegalAction = errors.New("the action is not legal now")
+)
+
+// An ActionError is returned by Validate and TryTakeAction for an action that
+// can't be taken.
+type ActionError struct {
+	Action *Action
+	// the player with priority, and when the action was tried
+	Player PlayerId
+	Turn   int
+	Phase  Phase
+	Err    error
+}
+
+func (e *ActionError) Error() string {
+	what := "action"
+	if e.Action != nil {
+		what = fmt.Sprintf("%s action", e.Action.Type)
+		if e.Action.Card != nil {
+			what = fmt.Sprintf("%s action with %s", e.Action.Type, e.Action.Card.Name)
+		}
+	}
+	return fmt.Sprintf("player %d, turn %d %s: %s: %v", e.Player, e.Turn, e.Phase, what, e.Err)
+}
+
+func (e *ActionError) Unwrap() error {
+	return e.Err
+}
+
+// Validate returns an *ActionError if the action can't be taken now. An action
+// is legal if it is the same as one of the actions Actions returns, though it
+// doesn't have to be the same pointer, so it can come from another process.
+func (g *Game) Validate(action *Action) error {
+	_, err := g.legalAction(action)
+	return err
+}
+
+// TryTakeAction is like TakeAction, but returns an *ActionError instead of
+// panicking for an action that isn't legal, and leaves the game unchanged.
+// It takes the legal action that is the same as the given one, so the action
+// can have its own copies of cards and costs.
+func (g *Game) TryTakeAction(action *Action) error {
+	legal, err := g.legalAction(action)
+	if err != nil {
+		return err
+	}
+	g.TakeAction(legal)
+	return nil
+}
+
+func (g *Game) legalAction(action *Action) (*Action, error) {
+	fail := func(err error) (*Action, error) {
+		return nil, &ActionError{Action: action, Player: g.PriorityId, Turn: g.Turn, Phase: g.Phase, Err: err}
+	}
+	if action == nil {
+		return fail(ErrNoAction)
+	}
+	if g.IsOver() {
+		return fail(ErrGameOver)
+	}
+	legal := g.findAction(action)
+	if legal == nil {
+		return fail(ErrIllegalAction)
+	}
+	return legal, nil
+}
+
+// findAction returns the legal action that is the same as the given one, or
+// nil if there is none.
+func (g *Game) findAction(action *Action) *Action {
+	hash := action.hash()
+	for _, a := range g.legalActions() {
+		if a.hash() == hash {
+			return a
+		}
+	}
+	return nil
+}
+
+// legalActions returns the actions for bots and then the ones for humans.
+// ChooseTargetAndMana actions are only for asking a human which action to
+// take, so they aren't legal to take.
+func (g *Game) legalActions() []*Action {
+	actions := g.Actions(false)
+	for _, a := range g.Actions(true) {
+		if a.Type != ChooseTargetAndMana {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
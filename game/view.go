@@ -0,0 +1,231 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A View is what one player can see of a game: everything public, their own
+// hand and the cards on top of their library they know the order of. It is
+// what a remote client, a fair bot or a training pipeline should get instead
+// of the whole Game, which has both hands and both libraries in order.
+//
+// A View has only names, ids and numbers, so it can be encoded as JSON.
+type View struct {
+	Viewer     PlayerId
+	Turn       int
+	Phase      Phase
+	PriorityId PlayerId
+	Players    [2]*PlayerView
+	// The permanents on the battlefield, in the order of each player's board.
+	Permanents []*PermanentView
+	// The stack, with the top last.
+	Stack []*StackObjectView
+	// The type of the effect the player with priority must make a choice
+	// about, like paying for Daze, or empty.
+	Choice string `json:",omitempty"`
+	// The actions the viewer can take, if they have priority.
+	Actions []ActionCode `json:",omitempty"`
+}
+
+type PlayerView struct {
+	Id    PlayerId
+	Life  int
+	Mana  Mana
+	Board []PermanentId
+
+	// Hand is nil for the viewer's opponent, who only shows HandSize and the
+	// Revealed cards.
+	Hand     []CardName
+	HandSize int
+	Revealed []CardName
+
+	LibrarySize int
+	// The cards on top of the library, in order, that the viewer knows, which
+	// are only ever the viewer's own.
+	KnownLibrary []CardName
+	// The cards on the bottom of the library, in order with the bottom last,
+	// that the viewer knows, like the ones they scried to the bottom.
+	KnownBottomLibrary []CardName `json:",omitempty"`
+	// How many cards on top and on the bottom of the library the player
+	// looked at or put there, like with Ponder or a scry, which both players
+	// see even if only the player knows what they are.
+	KnownTop    int
+	KnownBottom int
+	// The cards on top of the library that the player revealed, in order,
+	// like with Delver of Secrets.
+	RevealedLibrary []CardName `json:",omitempty"`
+	FailedToDraw    bool
+
+	Graveyard []CardName
+	Exile     []CardName
+
+	CreatureDied       bool
+	DamageThisTurn     int
+	KeptHand           bool
+	LandPlayedThisTurn int
+	Mulligans          int
+}
+
+type PermanentView struct {
+	Id         PermanentId
+	Name       CardName
+	Owner      PlayerId
+	Controller PlayerId
+	Tapped     bool
+	TurnPlayed int
+
+	Power              int
+	Toughness          int
+	Damage             int
+	Plus1Plus1Counters int
+	Attacking          bool
+	Blocking           PermanentId   `json:",omitempty"`
+	DamageOrder        []PermanentId `json:",omitempty"`
+
+	Auras  []PermanentId `json:",omitempty"`
+	Target PermanentId   `json:",omitempty"`
+	// The effects on it until the end of the turn, by what they do, like
+	// "+4/+4" or "untargetable".
+	TemporaryEffects []string `json:",omitempty"`
+}
+
+type StackObjectView struct {
+	Id     StackObjectId
+	Type   ActionType
+	Player PlayerId
+	// The spell, or the card the ability is from.
+	Card     CardName      `json:",omitempty"`
+	Source   PermanentId   `json:",omitempty"`
+	Target   string        `json:",omitempty"`
+	Selected []PermanentId `json:",omitempty"`
+	Ninjitsu bool          `json:",omitempty"`
+}
+
+// ViewFor returns what the player with the given id can see of the game.
+func (g *Game) ViewFor(id PlayerId) *View {
+	v := &View{
+		Viewer:     id,
+		Turn:       g.Turn,
+		Phase:      g.Phase,
+		PriorityId: g.PriorityId,
+		Permanents: []*PermanentView{},
+		Stack:      []*StackObjectView{},
+	}
+	for i, p := range g.Players {
+		v.Players[i] = p.viewFor(id)
+		for _, perm := range p.GetBoard() {
+			v.Permanents = append(v.Permanents, perm.view(p.Id))
+		}
+	}
+	for _, so := range g.GetStack() {
+		v.Stack = append(v.Stack, so.view())
+	}
+	if g.ChoiceEffect != nil {
+		v.Choice = g.ChoiceEffect.EffectType.String()
+	}
+	if g.PriorityId == id && !g.IsOver() {
+		for _, a := range g.Actions(false) {
+			v.Actions = append(v.Actions, encodeAction(a))
+		}
+	}
+	return v
+}
+
+func (p *Player) viewFor(viewer PlayerId) *PlayerView {
+	v := &PlayerView{
+		Id:                 p.Id,
+		Life:               p.Life,
+		Mana:               p.ManaPool,
+		Board:              copyPermanentIds(p.Board),
+		HandSize:           len(p.Hand),
+		Revealed:           copyCardNames(p.Revealed),
+		LibrarySize:        len(p.Deck.Cards),
+		KnownTop:           p.Deck.KnownTop,
+		KnownBottom:        p.Deck.KnownBottom,
+		RevealedLibrary:    p.Deck.Peek(p.Deck.RevealedTop),
+		FailedToDraw:       p.Deck.FailedToDraw,
+		Graveyard:          copyCardNames(p.Graveyard),
+		Exile:              copyCardNames(p.Exile),
+		CreatureDied:       p.CreatureDied,
+		DamageThisTurn:     p.DamageThisTurn,
+		KeptHand:           p.KeptHand,
+		LandPlayedThisTurn: p.LandPlayedThisTurn,
+		Mulligans:          p.Mulligans,
+	}
+	if viewer == p.Id {
+		v.Hand = copyCardNames(p.Hand)
+		v.KnownLibrary = p.Deck.Peek(p.Deck.KnownTop)
+		if known := Min(p.Deck.KnownBottom, len(p.Deck.Cards)); known > 0 {
+			v.KnownBottomLibrary = copyCardNames(p.Deck.Cards[len(p.Deck.Cards)-known:])
+		}
+	}
+	return v
+}
+
+func (p *Permanent) view(controller PlayerId) *PermanentView {
+	v := &PermanentView{
+		Id:                 p.Id,
+		Name:               p.Name,
+		Owner:              p.Owner,
+		Controller:         controller,
+		Tapped:             p.Tapped,
+		TurnPlayed:         p.TurnPlayed,
+		Power:              p.Power(),
+		Toughness:          p.Toughness(),
+		Damage:             p.Damage,
+		Plus1Plus1Counters: p.Plus1Plus1Counters,
+		Attacking:          p.Attacking,
+		Blocking:           p.Blocking,
+		DamageOrder:        copyPermanentIds(p.DamageOrder),
+		Auras:              copyPermanentIds(p.Auras),
+		Target:             p.Target,
+	}
+	for _, e := range p.TemporaryEffects {
+		v.TemporaryEffects = append(v.TemporaryEffects, temporaryEffectLabel(e))
+	}
+	return v
+}
+
+// temporaryEffectLabel describes what a temporary effect does to a
+// permanent, with its kicker if it was kicked.
+func temporaryEffectLabel(e *Effect) string {
+	power, toughness := e.Power, e.Toughness
+	untargetable, hexproof := e.Untargetable, e.Hexproof
+	if e.Kicker != nil {
+		power += e.Kicker.Power
+		toughness += e.Kicker.Toughness
+		untargetable = untargetable || e.Kicker.Untargetable
+		hexproof = hexproof || e.Kicker.Hexproof
+	}
+	parts := []string{}
+	if power != 0 || toughness != 0 {
+		parts = append(parts, fmt.Sprintf("%+d/%+d", power, toughness))
+	}
+	if untargetable {
+		parts = append(parts, "untargetable")
+	}
+	if hexproof {
+		parts = append(parts, "hexproof")
+	}
+	if len(parts) == 0 {
+		return e.EffectType.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (s *StackObject) view() *StackObjectView {
+	v := &StackObjectView{
+		Id:       s.Id,
+		Type:     s.Type,
+		Player:   s.Player,
+		Source:   s.Source,
+		Target:   targetCode(s.Target),
+		Selected: copyPermanentIds(s.Selected),
+		Ninjitsu: s.WithNinjitsu,
+	}
+	if s.Card != nil {
+		v.Card = s.Card.Name
+	}
+	return v
+}
@@ -0,0 +1,207 @@
+/*
+	Package rating keeps Glicko ratings of bots and decks in a file, so their
+	strength can be followed over time without playing every pairing again.
+
+	Each rating is for a strategy with its configuration playing a deck, like
+	"McstBot (C=3 playouts=200 workers=1 depth=50) with delver". A rating is a number
+	around 1500 and a deviation, which says how sure the rating is. A rating
+	gap of 200 means the better one wins about 75% of the time.
+
+	Glicko is described at http://www.glicko.net/glicko/glicko.pdf
+*/
+
+package rating
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/midrange/rogue/game"
+)
+
+const (
+	InitialRating    = 1500.0
+	InitialDeviation = 350.0
+
+	// Drift is how much the deviation grows before each game, so that a
+	// rating keeps changing when a bot changes.
+	Drift = 15.0
+)
+
+type Rating struct {
+	Rating    float64
+	Deviation float64
+	Wins      int
+	Losses    int
+	Draws     int
+}
+
+func (r *Rating) Games() int {
+	return r.Wins + r.Losses + r.Draws
+}
+
+// A Ledger holds the ratings, keyed by Key.
+type Ledger struct {
+	Ratings map[string]*Rating
+}
+
+// Key returns the key of the strategy playing the deck. It includes the
+// strategy's configuration if it has one.
+func Key(s game.Strategy, deck string) string {
+	if c, ok := s.(game.Configurable); ok {
+		return fmt.Sprintf("%s (%s) with %s", s, c.Config(), deck)
+	}
+	return fmt.Sprintf("%s with %s", s, deck)
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{Ratings: map[string]*Rating{}}
+}
+
+// Load reads the ledger in the file at path. A file that doesn't exist is an
+// empty ledger.
+func Load(path string) (*Ledger, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewLedger(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := NewLedger()
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return l, nil
+}
+
+// Save writes the ledger to the file at path. It writes a new file and then
+// renames it, so a ledger is never left half written.
+func (l *Ledger) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Rating returns the rating for the key, adding a new one if there is none.
+func (l *Ledger) Rating(key string) *Rating {
+	r, ok := l.Ratings[key]
+	if !ok {
+		r = &Rating{Rating: InitialRating, Deviation: InitialDeviation}
+		l.Ratings[key] = r
+	}
+	return r
+}
+
+// RecordGame updates the ratings for the result of PlayGame, where onThePlay
+// and onTheDraw are the keys of the two players.
+func (l *Ledger) RecordGame(onThePlay string, onTheDraw string, winner game.PlayerId) {
+	switch winner {
+	case game.OnThePlay:
+		l.Record(onThePlay, onTheDraw, 1)
+	case game.OnTheDraw:
+		l.Record(onThePlay, onTheDraw, 0)
+	default:
+		l.Record(onThePlay, onTheDraw, 0.5)
+	}
+}
+
+// Record updates the ratings of a and b for a game where a scored 1 for a
+// win, 0.5 for a draw or 0 for a loss. Each game is its own rating period.
+func (l *Ledger) Record(a string, b string, score float64) {
+	ra, rb := l.Rating(a), l.Rating(b)
+	for _, r := range []*Rating{ra, rb} {
+		r.Deviation = math.Min(math.Sqrt(r.Deviation*r.Deviation+Drift*Drift), InitialDeviation)
+	}
+	newA := update(*ra, *rb, score)
+	newB := update(*rb, *ra, 1-score)
+	ra.Rating, ra.Deviation = newA.Rating, newA.Deviation
+	rb.Rating, rb.Deviation = newB.Rating, newB.Deviation
+
+	switch score {
+	case 1:
+		ra.Wins++
+		rb.Losses++
+	case 0:
+		ra.Losses++
+		rb.Wins++
+	default:
+		ra.Draws++
+		rb.Draws++
+	}
+}
+
+// q is the Glicko constant ln(10)/400.
+var q = math.Ln10 / 400
+
+// g reduces the weight of a game against an opponent with an uncertain rating.
+func g(deviation float64) float64 {
+	return 1 / math.Sqrt(1+3*q*q*deviation*deviation/(math.Pi*math.Pi))
+}
+
+// Expected returns the expected score of a rating against another one.
+func Expected(r Rating, opponent Rating) float64 {
+	return 1 / (1 + math.Pow(10, -g(opponent.Deviation)*(r.Rating-opponent.Rating)/400))
+}
+
+// update returns the rating r gets for scoring score against opponent.
+func update(r Rating, opponent Rating, score float64) Rating {
+	gj := g(opponent.Deviation)
+	e := Expected(r, opponent)
+	dSquared := 1 / (q * q * gj * gj * e * (1 - e))
+	precision := 1/(r.Deviation*r.Deviation) + 1/dSquared
+	r.Rating += q / precision * gj * (score - e)
+	r.Deviation = math.Sqrt(1 / precision)
+	return r
+}
+
+// Leaderboard returns the keys of the ratings, best first.
+func (l *Ledger) Leaderboard() []string {
+	keys := []string{}
+	for key := range l.Ratings {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, rj := l.Ratings[keys[i]], l.Ratings[keys[j]]
+		if ri.Rating != rj.Rating {
+			return ri.Rating > rj.Rating
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// WriteLeaderboard writes the ratings as a table, best first. The interval is
+// two deviations on either side, where the true rating is 95% likely to be.
+func (l *Ledger) WriteLeaderboard(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Rank\tRating\t95% interval\tGames\tWins\tLosses\tDraws\tStrategy")
+	for i, key := range l.Leaderboard() {
+		r := l.Ratings[key]
+		fmt.Fprintf(tw, "%d\t%.0f\t%.0f - %.0f\t%d\t%d\t%d\t%d\t%s\n", i+1, r.Rating,
+			r.Rating-2*r.Deviation, r.Rating+2*r.Deviation, r.Games(), r.Wins, r.Losses, r.Draws, key)
+	}
+	return tw.Flush()
+}
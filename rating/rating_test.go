@@ -0,0 +1,68 @@
+package rating
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/midrange/rogue/game"
+)
+
+func TestRecord(t *testing.T) {
+	l := NewLedger()
+	for i := 0; i < 10; i++ {
+		l.RecordGame("winner", "loser", game.OnThePlay)
+	}
+	l.RecordGame("winner", "drawer", game.NoPlayerId)
+	winner, loser, drawer := l.Rating("winner"), l.Rating("loser"), l.Rating("drawer")
+	if winner.Rating <= InitialRating || loser.Rating >= InitialRating {
+		t.Fatalf("expected the winner to gain rating, got %+v and %+v", winner, loser)
+	}
+	if winner.Deviation >= InitialDeviation || loser.Deviation >= InitialDeviation {
+		t.Fatalf("expected the deviations to shrink, got %+v and %+v", winner, loser)
+	}
+	if winner.Wins != 10 || winner.Draws != 1 || loser.Losses != 10 || drawer.Draws != 1 {
+		t.Fatalf("wrong counts: %+v %+v %+v", winner, loser, drawer)
+	}
+	// a draw against a much lower rating is a bad result
+	if drawer.Rating <= InitialRating {
+		t.Fatalf("expected the drawer to gain rating, got %+v", drawer)
+	}
+	board := l.Leaderboard()
+	if strings.Join(board, " ") != "winner drawer loser" {
+		t.Fatalf("wrong leaderboard: %v", board)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratings.json")
+	l, err := Load(path)
+	if err != nil || len(l.Ratings) != 0 {
+		t.Fatalf("expected a missing file to be an empty ledger, got %v %v", l, err)
+	}
+	l.RecordGame("a", "b", game.OnTheDraw)
+	if err := l.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loaded.Rating("b") != *l.Rating("b") || len(loaded.Ratings) != 2 {
+		t.Fatalf("expected %+v, got %+v", l.Ratings, loaded.Ratings)
+	}
+}
+
+func TestKey(t *testing.T) {
+	config := game.DefaultBotConfig(1)
+	config.Playouts = 200
+	config.Workers = 1
+	mcst, _ := game.NewStrategy("mcst", config)
+	attack, _ := game.NewStrategy("attack", config)
+	if key := Key(mcst, "delver"); !strings.Contains(key, "playouts=200") {
+		t.Fatalf("expected the key to have the configuration, got %q", key)
+	}
+	if key := Key(attack, "stompy"); key != "AttackBot with stompy" {
+		t.Fatalf("got %q", key)
+	}
+}
@@ -0,0 +1,129 @@
+package tournament
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// A Standing is the results of all the games of one entry.
+type Standing struct {
+	Entry Entry
+	Games int
+	Wins  int
+	Draws int
+}
+
+func (s *Standing) WinRate() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+	return (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games)
+}
+
+// Standings adds up the pairings for each entry, best win rate first.
+func Standings(pairings []*Pairing) []*Standing {
+	byEntry := map[Entry]*Standing{}
+	standings := []*Standing{}
+	add := func(e Entry, games, wins, draws int) {
+		s, ok := byEntry[e]
+		if !ok {
+			s = &Standing{Entry: e}
+			byEntry[e] = s
+			standings = append(standings, s)
+		}
+		s.Games += games
+		s.Wins += wins
+		s.Draws += draws
+	}
+	for _, p := range pairings {
+		add(p.A, p.Games, p.Wins, p.Draws)
+		add(p.B, p.Games, p.Losses(), p.Draws)
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].WinRate() > standings[j].WinRate()
+	})
+	return standings
+}
+
+// WriteTable writes the standings and the results of each pairing as tables
+// for people to read.
+func WriteTable(w io.Writer, pairings []*Pairing) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Entry\tGames\tWins\tDraws\tWin rate\t95% interval")
+	for _, s := range Standings(pairings) {
+		low, high := wilson(s.WinRate(), s.Games)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f%%\t%.1f%% - %.1f%%\n",
+			s.Entry, s.Games, s.Wins, s.Draws, 100*s.WinRate(), 100*low, 100*high)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "A\tB\tGames\tA wins\tDraws\tA win rate\t95% interval\tA on the play\tA on the draw\tAverage turns\tFailed")
+	for _, p := range pairings {
+		low, high := p.Interval()
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%.1f%%\t%.1f%% - %.1f%%\t%d/%d\t%d/%d\t%.1f\t%d\n",
+			p.A, p.B, p.Games, p.Wins, p.Draws, 100*p.WinRate(), 100*low, 100*high,
+			p.WinsOnThePlay, p.GamesOnThePlay, p.WinsOnTheDraw, p.GamesOnTheDraw, p.AverageTurns(), p.Failed)
+	}
+	return tw.Flush()
+}
+
+// PairingReport is a Pairing with its computed statistics, for JSON.
+type PairingReport struct {
+	*Pairing
+	Losses       int
+	WinRate      float64
+	Low          float64
+	High         float64
+	AverageTurns float64
+}
+
+// WriteJSON writes the pairings and, if there are any, the games.
+func WriteJSON(w io.Writer, pairings []*Pairing, games []*GameResult) error {
+	reports := []*PairingReport{}
+	for _, p := range pairings {
+		low, high := p.Interval()
+		reports = append(reports, &PairingReport{
+			Pairing:      p,
+			Losses:       p.Losses(),
+			WinRate:      p.WinRate(),
+			Low:          low,
+			High:         high,
+			AverageTurns: p.AverageTurns(),
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Pairings []*PairingReport
+		Games    []*GameResult `json:",omitempty"`
+	}{reports, games})
+}
+
+// WriteCSV writes a row for each pairing.
+func WriteCSV(w io.Writer, pairings []*Pairing) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"a", "b", "games", "wins", "draws", "losses", "win_rate", "low", "high",
+		"games_on_the_play", "wins_on_the_play", "games_on_the_draw", "wins_on_the_draw", "average_turns",
+		"failed"})
+	for _, p := range pairings {
+		low, high := p.Interval()
+		cw.Write([]string{
+			p.A.String(), p.B.String(), strconv.Itoa(p.Games), strconv.Itoa(p.Wins),
+			strconv.Itoa(p.Draws), strconv.Itoa(p.Losses()), formatFloat(p.WinRate()),
+			formatFloat(low), formatFloat(high), strconv.Itoa(p.GamesOnThePlay),
+			strconv.Itoa(p.WinsOnThePlay), strconv.Itoa(p.GamesOnTheDraw),
+			strconv.Itoa(p.WinsOnTheDraw), formatFloat(p.AverageTurns()),
+			strconv.Itoa(p.Failed),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
@@ -0,0 +1,337 @@
+/*
+	Package tournament plays many games between strategies and decks, to tell
+	whether a change to a bot or a deck makes it win more.
+
+	Each Entry is a registered strategy playing a registered deck or a
+	decklist file. A round robin pairs every entry with every other one, and a
+	gauntlet pairs the first entry with each of the others. Each pairing plays
+	an even number of games, with each entry on the play for half of them.
+*/
+
+package tournament
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/midrange/rogue/game"
+)
+
+// An Entry is a strategy playing a deck, written as "strategy:deck".
+type Entry struct {
+	Strategy string
+	Deck     string
+}
+
+func (e Entry) String() string {
+	return e.Strategy + ":" + e.Deck
+}
+
+// ParseEntry reads an entry like "mcst:delver" or "attack:decks/stompy.txt".
+func ParseEntry(s string) (Entry, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Entry{}, fmt.Errorf("expected an entry like mcst:delver, got %q", s)
+	}
+	return Entry{Strategy: parts[0], Deck: parts[1]}, nil
+}
+
+type Format int
+
+const (
+	RoundRobin Format = iota
+	Gauntlet
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "roundrobin", "round-robin":
+		return RoundRobin, nil
+	case "gauntlet":
+		return Gauntlet, nil
+	}
+	return RoundRobin, fmt.Errorf("unknown format %q, expected roundrobin or gauntlet", s)
+}
+
+type Config struct {
+	Entries []Entry
+	Format  Format
+	// Games is the number of games of each pairing. An odd number is rounded
+	// up so that both entries are on the play equally often.
+	Games int
+	// Parallel is how many games are played at once, the number of CPUs by
+	// default.
+	Parallel int
+	// Seed decides the seed of every game and bot, so a tournament can be
+	// played again exactly if its bots use Playouts rather than a time limit.
+	Seed int64
+	// Bot configures the strategies. Its Seed is replaced for each game, and
+	// the bots are always Quiet.
+	Bot game.BotConfig
+}
+
+// A Pairing is the results of the games between two entries, from A's side.
+type Pairing struct {
+	A, B  Entry
+	Games int
+	Wins  int // for A
+	Draws int
+	// Games and wins of A on the play and on the draw.
+	GamesOnThePlay int
+	WinsOnThePlay  int
+	GamesOnTheDraw int
+	WinsOnTheDraw  int
+	// The total of the number of turns of each game.
+	Turns int
+	// Failed is the number of games that aren't counted because a bot
+	// failed during them, like an ExternalBot whose program crashed.
+	Failed int
+}
+
+// Losses returns the number of games A lost.
+func (p *Pairing) Losses() int {
+	return p.Games - p.Wins - p.Draws
+}
+
+// WinRate returns the fraction of the games A won, counting draws as half a win.
+func (p *Pairing) WinRate() float64 {
+	if p.Games == 0 {
+		return 0
+	}
+	return (float64(p.Wins) + float64(p.Draws)/2) / float64(p.Games)
+}
+
+// Interval returns the 95% Wilson score interval of A's win rate.
+func (p *Pairing) Interval() (float64, float64) {
+	return wilson(p.WinRate(), p.Games)
+}
+
+// AverageTurns returns the average length of a game in turns.
+func (p *Pairing) AverageTurns() float64 {
+	if p.Games == 0 {
+		return 0
+	}
+	return float64(p.Turns) / float64(p.Games)
+}
+
+// wilson returns the 95% Wilson score interval of a rate observed over n trials.
+func wilson(rate float64, n int) (float64, float64) {
+	if n == 0 {
+		return 0, 1
+	}
+	const z = 1.96
+	trials := float64(n)
+	center := (rate + z*z/(2*trials)) / (1 + z*z/trials)
+	half := z * math.Sqrt(rate*(1-rate)/trials+z*z/(4*trials*trials)) / (1 + z*z/trials)
+	low, high := math.Max(0, center-half), math.Min(1, center+half)
+	// without rounding errors, these are exact
+	if rate == 0 {
+		low = 0
+	}
+	if rate == 1 {
+		high = 1
+	}
+	return low, high
+}
+
+// Pairings returns the pairings the format makes of the entries.
+func (c *Config) Pairings() []*Pairing {
+	pairings := []*Pairing{}
+	for i, a := range c.Entries {
+		for j := i + 1; j < len(c.Entries); j++ {
+			if c.Format == Gauntlet && i > 0 {
+				break
+			}
+			pairings = append(pairings, &Pairing{A: a, B: c.Entries[j]})
+		}
+	}
+	return pairings
+}
+
+// A game of a pairing, with A on the play if aOnThePlay.
+type gameSpec struct {
+	pairing    *Pairing
+	aOnThePlay bool
+	seed       int64
+	botSeeds   [2]int64
+}
+
+// A GameResult is the outcome of one game of a tournament.
+type GameResult struct {
+	A, B       Entry
+	Seed       int64
+	AOnThePlay bool
+	// Winner is "A", "B" or "draw".
+	Winner string
+	Turns  int
+	// Error is why a bot failed during the game, which then doesn't count.
+	Error string `json:",omitempty"`
+}
+
+// Run plays the tournament and returns the results of each pairing, and of
+// each game in the order they were scheduled.
+func Run(config Config) ([]*Pairing, []*GameResult, error) {
+	if len(config.Entries) < 2 {
+		return nil, nil, fmt.Errorf("a tournament needs at least two entries")
+	}
+	config.Bot.Quiet = true
+	decks := map[string]*game.Deck{}
+	for _, e := range config.Entries {
+		strategy, err := game.NewStrategy(e.Strategy, config.Bot)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := strategy.(*game.Human); ok {
+			return nil, nil, fmt.Errorf("a tournament can't have a human in it")
+		}
+		if err := botError(strategy); err != nil {
+			return nil, nil, err
+		}
+		if decks[e.Deck] == nil {
+			deck, err := game.LoadDeck(e.Deck)
+			if err != nil {
+				return nil, nil, err
+			}
+			decks[e.Deck] = deck
+		}
+	}
+
+	// all the seeds are chosen up front, so they don't depend on the order
+	// the games finish in
+	rng := game.NewRng(config.Seed)
+	pairings := config.Pairings()
+	specs := []*gameSpec{}
+	for _, p := range pairings {
+		for i := 0; i < (config.Games+1)/2*2; i++ {
+			specs = append(specs, &gameSpec{
+				pairing:    p,
+				aOnThePlay: i%2 == 0,
+				seed:       rng.Int63(),
+				botSeeds:   [2]int64{rng.Int63(), rng.Int63()},
+			})
+		}
+	}
+
+	parallel := config.Parallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+	results := make([]*GameResult, len(specs))
+	errs := make([]error, len(specs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i], errs[i] = play(specs[i], decks, config.Bot)
+			}
+		}()
+	}
+	for i := range specs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for i, r := range results {
+		p := specs[i].pairing
+		if r.Error != "" {
+			p.Failed++
+			continue
+		}
+		p.Games++
+		p.Turns += r.Turns
+		won := r.Winner == "A"
+		if r.AOnThePlay {
+			p.GamesOnThePlay++
+		} else {
+			p.GamesOnTheDraw++
+		}
+		switch {
+		case won && r.AOnThePlay:
+			p.Wins++
+			p.WinsOnThePlay++
+		case won:
+			p.Wins++
+			p.WinsOnTheDraw++
+		case r.Winner == "draw":
+			p.Draws++
+		}
+	}
+	return pairings, results, nil
+}
+
+func play(spec *gameSpec, decks map[string]*game.Deck, bot game.BotConfig) (*GameResult, error) {
+	p := spec.pairing
+	bot.Seed = spec.botSeeds[0]
+	a, err := game.NewStrategy(p.A.Strategy, bot)
+	if err != nil {
+		return nil, err
+	}
+	bot.Seed = spec.botSeeds[1]
+	b, err := game.NewStrategy(p.B.Strategy, bot)
+	if err != nil {
+		if c, ok := a.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+	// external bots run a program for each game
+	for _, s := range []game.Strategy{a, b} {
+		if c, ok := s.(io.Closer); ok {
+			defer c.Close()
+		}
+	}
+
+	var g *game.Game
+	var winner game.PlayerId
+	aId := game.OnThePlay
+	if spec.aOnThePlay {
+		g = game.NewGame(decks[p.A.Deck].Copy(), decks[p.B.Deck].Copy(), spec.seed)
+		winner = game.PlayGame(g, a, b, false)
+	} else {
+		aId = game.OnTheDraw
+		g = game.NewGame(decks[p.B.Deck].Copy(), decks[p.A.Deck].Copy(), spec.seed)
+		winner = game.PlayGame(g, b, a, false)
+	}
+
+	result := &GameResult{
+		A:          p.A,
+		B:          p.B,
+		Seed:       spec.seed,
+		AOnThePlay: spec.aOnThePlay,
+		Winner:     "B",
+		Turns:      g.Turn + 1,
+	}
+	if winner == aId {
+		result.Winner = "A"
+	} else if winner == game.NoPlayerId {
+		result.Winner = "draw"
+	}
+	if err := botError(a); err != nil {
+		result.Error = fmt.Sprintf("%s: %v", p.A, err)
+	} else if err := botError(b); err != nil {
+		result.Error = fmt.Sprintf("%s: %v", p.B, err)
+	}
+	return result, nil
+}
+
+// botError returns why the strategy failed, if it is a bot that can fail and
+// have another strategy play for it.
+func botError(s game.Strategy) error {
+	if b, ok := s.(*game.ExternalBot); ok {
+		return b.Err
+	}
+	return nil
+}
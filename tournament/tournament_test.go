@@ -0,0 +1,100 @@
+package tournament
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	config := Config{
+		Entries: []Entry{{"random", "stompy"}, {"attack", "stompy"}, {"attack", "delver"}},
+		Games:   3,
+		Seed:    1,
+	}
+	pairings, games, err := Run(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairings) != 3 || len(games) != 12 {
+		t.Fatalf("expected 3 pairings of 4 games, got %d pairings and %d games", len(pairings), len(games))
+	}
+	for _, p := range pairings {
+		if p.GamesOnThePlay != 2 || p.GamesOnTheDraw != 2 ||
+			p.Wins != p.WinsOnThePlay+p.WinsOnTheDraw {
+			t.Fatalf("expected A to be on the play for half of the games, got %+v", p)
+		}
+	}
+
+	again, _, _ := Run(config)
+	for i, p := range again {
+		if *p != *pairings[i] {
+			t.Fatal("expected the same seed to give the same results")
+		}
+	}
+
+	config.Format = Gauntlet
+	if len(config.Pairings()) != 2 {
+		t.Fatal("expected a gauntlet to pair the first entry with each other one")
+	}
+
+	var b bytes.Buffer
+	if err := WriteJSON(&b, pairings, games); err != nil {
+		t.Fatal(err)
+	}
+	var report struct{ Pairings []map[string]interface{} }
+	if err := json.Unmarshal(b.Bytes(), &report); err != nil || len(report.Pairings) != 3 {
+		t.Fatalf("expected a JSON report of 3 pairings, got %v", err)
+	}
+	b.Reset()
+	WriteCSV(&b, pairings)
+	if lines := strings.Count(b.String(), "\n"); lines != 4 {
+		t.Fatalf("expected a CSV header and 3 rows, got %d lines", lines)
+	}
+}
+
+func TestRunDropsFailedGames(t *testing.T) {
+	config := Config{
+		Entries: []Entry{{"external", "stompy"}, {"attack", "delver"}},
+		Games:   2,
+		Seed:    1,
+	}
+	if _, _, err := Run(config); err == nil {
+		t.Fatal("expected an external entry without a command to be an error")
+	}
+
+	config.Bot.Command = "./no-such-bot"
+	pairings, games, err := Run(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pairings[0].Games != 0 || pairings[0].Failed != 2 {
+		t.Fatalf("expected the games the external bot failed not to count, got %+v", pairings[0])
+	}
+	for _, g := range games {
+		if !strings.HasPrefix(g.Error, "external:stompy: ") {
+			t.Fatalf("expected the game to say the external bot failed, got %q", g.Error)
+		}
+	}
+}
+
+func TestWilson(t *testing.T) {
+	low, high := wilson(0.5, 100)
+	if low < 0.40 || low > 0.41 || high < 0.59 || high > 0.60 {
+		t.Fatalf("expected about 40%% - 60%%, got %f - %f", low, high)
+	}
+	if low, high := wilson(1, 10); high != 1 || low < 0.7 {
+		t.Fatalf("expected a perfect record to reach 100%%, got %f - %f", low, high)
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	e, err := ParseEntry("mcst:decks/delver.txt")
+	if err != nil || e.Strategy != "mcst" || e.Deck != "decks/delver.txt" {
+		t.Fatalf("unexpected entry %v, %v", e, err)
+	}
+	if _, err := ParseEntry("mcst"); err == nil {
+		t.Fatal("expected an entry without a deck to be an error")
+	}
+}